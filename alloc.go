@@ -0,0 +1,153 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"github.com/gonum/blas/blas64"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// An Allocator provides the storage for the results of evaluating matrix
+// expressions.  Intermediate results are returned to the allocator with Put as
+// soon as the expression that uses them is done with them.  Allocators must
+// be safe for concurrent use.
+type Allocator interface {
+	Get(n int) []float64 // Get returns a slice of n zeros.
+	Put([]float64)       // Put returns a slice which is no longer in use.
+}
+
+// Heap is an Allocator that always allocates new memory, and leaves memory
+// that is no longer in use to the garbage collector.  Use SetAllocator(Heap) to
+// opt out of pooling.
+var Heap Allocator = heapAllocator{}
+
+type heapAllocator struct{}
+
+func (heapAllocator) Get(n int) []float64 { return make([]float64, n) }
+func (heapAllocator) Put([]float64)       {}
+
+// Pool is an Allocator which keeps slices that are no longer in use in
+// buckets by size, so that later evaluations can reuse them.  It is the
+// default Allocator.
+type Pool struct {
+	// bucket i holds slices with capacity of at least 1<<i.
+	buckets [64]sync.Pool
+}
+
+// NewPool creates a new, empty Pool.
+func NewPool() *Pool {
+	return &Pool{}
+}
+
+// Get returns a slice of n zeros.
+func (p *Pool) Get(n int) []float64 {
+	if n == 0 {
+		return []float64{}
+	}
+	// round up to the next power of two
+	b := bits.Len(uint(n - 1))
+	if v, ok := p.buckets[b].Get().(*[]float64); ok {
+		s := (*v)[:n]
+		for i := range s {
+			s[i] = 0
+		}
+		return s
+	}
+	return make([]float64, n, 1<<uint(b))
+}
+
+// Put returns a slice which is no longer in use to the pool.
+func (p *Pool) Put(s []float64) {
+	if cap(s) == 0 {
+		return
+	}
+	// round down to the previous power of two
+	b := bits.Len(uint(cap(s))) - 1
+	s = s[:cap(s)]
+	p.buckets[b].Put(&s)
+}
+
+// allocator holds the current Allocator.
+var allocator atomic.Value
+
+type allocatorBox struct {
+	Allocator
+}
+
+func init() {
+	allocator.Store(allocatorBox{NewPool()})
+}
+
+// SetAllocator changes the Allocator used to evaluate matrix expressions.  A
+// nil Allocator is the same as Heap.
+func SetAllocator(a Allocator) {
+	if a == nil {
+		a = Heap
+	}
+	allocator.Store(allocatorBox{a})
+}
+
+// getAllocator returns the current Allocator.
+func getAllocator() Allocator {
+	return allocator.Load().(allocatorBox).Allocator
+}
+
+// ownedResult is implemented by the expressions in this package whose
+// evaluation always produces a new result, which nothing else refers to.
+// Expressions that evaluate to a literal which may be in use elsewhere, such
+// as literals, Async, Memo, and Placeholder, don't implement it, and neither
+// does any expression from outside of the package.
+type ownedResult interface {
+	MatrixExp
+	ownedResult()
+}
+
+// owns determines if whoever evaluates m owns the result, which means that
+// nothing else refers to it and it can be released once it has been consumed.
+func owns(m MatrixExp) bool {
+	_, ok := m.(ownedResult)
+	return ok
+}
+
+// release returns the result of evaluating m to the allocator, if the caller
+// owns it.  The result must not be used afterwards.
+func release(m MatrixExp, result MatrixLiteral) {
+	if !owns(m) {
+		return
+	}
+	if g, ok := result.(*General); ok {
+		getAllocator().Put(g.Data)
+	}
+}
+
+// temporary returns the result of evaluating m if the caller owns it and it is
+// a compact General, so that the caller can overwrite it in place instead of
+// allocating a new result.  Literals supplied by the user are never
+// temporaries.
+func temporary(m MatrixExp, result MatrixLiteral) (blas64.General, bool) {
+	if !owns(m) {
+		return blas64.General{}, false
+	}
+	g, ok := result.(*General)
+	if !ok || g.Stride != g.Cols {
+		return blas64.General{}, false
+	}
+	return g.General, true
+}
+
+// writable returns the result of evaluating m as a compact matrix that the
+// caller can overwrite: either the result itself, if it is a temporary, or a
+// copy of it, in which case the result is released.  In both cases the caller
+// owns the returned matrix.
+func writable(m MatrixExp, result MatrixLiteral) blas64.General {
+	if g, ok := temporary(m, result); ok {
+		return g
+	}
+	g := copyGeneral(result.AsGeneral())
+	release(m, result)
+	return g
+}
@@ -0,0 +1,188 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"sync"
+	"testing"
+)
+
+// checkAllocator is an Allocator which records which slices it has handed out,
+// so that it can find slices which are released twice or which it never
+// allocated.
+type checkAllocator struct {
+	mu   sync.Mutex
+	out  map[*float64]bool
+	gets []int
+	puts int
+	errs []string
+}
+
+func (a *checkAllocator) Get(n int) []float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.gets = append(a.gets, n)
+	s := make([]float64, n, n+1)
+	a.out[&s[:cap(s)][0]] = true
+	return s
+}
+
+func (a *checkAllocator) Put(s []float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := &s[:cap(s)][0]
+	if !a.out[p] {
+		a.errs = append(a.errs, "released a slice that is not in use")
+		return
+	}
+	a.out[p] = false
+	a.puts++
+}
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+	p := NewPool()
+	for _, n := range []int{1, 3, 4, 5, 100} {
+		s := p.Get(n)
+		if len(s) != n {
+			t.Errorf("Get(%v) has length %v, want %v", n, len(s), n)
+		}
+		for i := range s {
+			s[i] = 1
+		}
+		p.Put(s)
+		s = p.Get(n)
+		for i, v := range s {
+			if v != 0 {
+				t.Errorf("Get(%v)[%v] equals %v, want 0", n, i, v)
+			}
+		}
+	}
+	if s := p.Get(0); len(s) != 0 {
+		t.Errorf("Get(0) has length %v, want 0", len(s))
+	}
+}
+
+func TestRelease(t *testing.T) {
+	// This replaces the package allocator, so it can't run in parallel.
+	a := &checkAllocator{out: make(map[*float64]bool)}
+	SetAllocator(a)
+	defer SetAllocator(NewPool())
+
+	A := &General{rnd(4, 4)}
+	B := &General{rnd(4, 4)}
+	u := &General{rnd(4, 1)}
+	v := &General{rnd(4, 1)}
+	for _, m := range []MatrixExp{
+		A.Mul(B).Add(A.T().Scale(2)).MulElem(B.Sub(A)).DivElem(B),
+		A.Mul(B).Mul(A.Add(B)),
+		&Inv{A.Add(B.Scale(4))},
+		&Pow{M: A.Add(B), K: 5},
+		&Pow{M: A.Add(B), K: -2},
+		&Expm{A.Scale(3)},
+		&Mul{Left: &Kron{Left: &General{rnd(2, 2)}, Right: &General{rnd(2, 2)}}, Right: A.Add(B)},
+		&Mul{Left: &LowRank{U: u.Scale(2), V: v}, Right: A.Add(B)},
+		&Ger{A: A.Add(B), Alpha: 2, U: u.Scale(2), V: v.Scale(3)},
+		&Mul{Left: &Outer{U: u.Scale(2), V: v.Scale(3)}, Right: A.Mul(B)},
+		&HCat{Left: A.Add(B), Right: u.Scale(2)},
+	} {
+		a.puts = 0
+		got := m.Eval()
+		for _, err := range a.errs {
+			t.Errorf("%v: %v", m, err)
+		}
+		a.errs = nil
+		if g, ok := got.(*General); ok && !a.out[&g.Data[:cap(g.Data)][0]] {
+			t.Errorf("%v released its own result", m)
+		}
+		if a.puts == 0 {
+			t.Errorf("%v released no intermediate results", m)
+		}
+
+		SetAllocator(Heap)
+		want := m.Eval()
+		SetAllocator(a)
+		if !near(got, want, 1e-9) {
+			t.Errorf("%v equals %v, want %v", m, got, want)
+		}
+	}
+}
+
+func TestInPlace(t *testing.T) {
+	// This replaces the package allocator, so it can't run in parallel.
+	a := &checkAllocator{out: make(map[*float64]bool)}
+	SetAllocator(a)
+	defer SetAllocator(NewPool())
+
+	A := wellConditioned(4)
+	B := &General{rnd(4, 4)}
+	u := &General{rnd(4, 1)}
+	orig := A.AsVector()
+	for _, tt := range []struct {
+		m    MatrixExp
+		want []int
+	}{
+		// the product is the only full allocation, and is reused by the
+		// element-wise expression and the transpose.
+		{m: A.Mul(B).Scale(2).Add(B).T(), want: []int{16, 4}},
+		{m: &Ger{A: A.Mul(B), Alpha: 2, U: u, V: u}, want: []int{16}},
+		// literals are never overwritten.
+		{m: A.Scale(2).T(), want: []int{16}},
+		{m: &Ger{A: A, Alpha: 2, U: u, V: u}, want: []int{16}},
+		{m: &Inv{A}, want: []int{16, 16}},
+		{m: &Expm{A}, want: nil},
+	} {
+		a.gets = nil
+		tt.m.Eval()
+		if tt.want != nil && !equalInts(a.gets, tt.want) {
+			t.Errorf("%v allocated %v, want %v", tt.m, a.gets, tt.want)
+		}
+		for i, v := range A.AsVector() {
+			if v != orig[i] {
+				t.Errorf("%v overwrote %v", tt.m, A)
+				break
+			}
+		}
+	}
+}
+
+// shared is an expression from outside of the package, which evaluates to a
+// literal that the user still holds.
+type shared struct {
+	MatrixExp
+}
+
+func TestNotOwned(t *testing.T) {
+	// This replaces the package allocator, so it can't run in parallel.
+	a := &checkAllocator{out: make(map[*float64]bool)}
+	SetAllocator(a)
+	defer SetAllocator(NewPool())
+
+	A := wellConditioned(4)
+	u := &General{rnd(4, 1)}
+	s := &shared{A}
+	orig := A.AsVector()
+	for _, m := range []MatrixExp{
+		&Scale{C: 2, M: s},
+		&Add{Left: s, Right: A},
+		&T{s},
+		&Inv{s},
+		&Expm{s},
+		&Ger{A: s, Alpha: 2, U: u, V: u},
+		&Mul{Left: s, Right: A},
+	} {
+		a.errs = nil
+		m.Eval()
+		for _, err := range a.errs {
+			t.Errorf("%v: %v", m, err)
+		}
+		for i, v := range A.AsVector() {
+			if v != orig[i] {
+				t.Errorf("%v overwrote %v", m, A)
+				break
+			}
+		}
+	}
+}
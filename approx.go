@@ -0,0 +1,176 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// ToleranceMode determines how the difference between two values is measured.
+type ToleranceMode int
+
+const (
+	// Absolute tolerances bound |a - b|.
+	Absolute ToleranceMode = iota
+	// Relative tolerances bound |a - b| / max(|a|, |b|).
+	Relative
+	// ULP tolerances bound the number of float64 values between a and b,
+	// which is the number of units in the last place that they differ by.
+	ULP
+)
+
+// NaNMode determines how NaN values are compared.
+type NaNMode int
+
+const (
+	// NaNNotEqual follows IEEE 754, where NaN is not equal to anything,
+	// including NaN.
+	NaNNotEqual NaNMode = iota
+	// NaNEqual treats NaN as equal to NaN, but not to any other value.
+	NaNEqual
+	// NaNIgnore skips any element where either value is NaN.
+	NaNIgnore
+)
+
+// Tolerance determines how close two values have to be to be considered
+// equal.  Values which are exactly equal, including infinities of the same
+// sign, are always equal.
+type Tolerance struct {
+	Mode ToleranceMode
+	Tol  float64
+	NaN  NaNMode
+}
+
+// Difference is an element where two matrices differ by more than the
+// tolerance.  Err is the difference, measured according to the tolerance mode.
+type Difference struct {
+	Row, Col int
+	A, B     float64
+	Err      float64
+}
+
+// Diff reports the differences between two matrices.
+type Diff struct {
+	Tol   Tolerance
+	Dims  error // non-nil if the matrices have different dimensions
+	Elems []Difference
+}
+
+// Equal determines if the matrices were equal to within the tolerance.
+func (d *Diff) Equal() bool {
+	return d.Dims == nil && len(d.Elems) == 0
+}
+
+// String implements the Stringer interface.  It lists the elements which
+// differ, one per line.
+func (d *Diff) String() string {
+	if d.Dims != nil {
+		return d.Dims.Error()
+	}
+	if len(d.Elems) == 0 {
+		return "equal"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d elements differ:", len(d.Elems))
+	for _, e := range d.Elems {
+		fmt.Fprintf(&b, "\n(%d, %d): %v vs %v, off by %v", e.Row, e.Col, e.A, e.B, e.Err)
+	}
+	return b.String()
+}
+
+// Compare evaluates two matrices and reports the elements which differ by
+// more than the tolerance.
+func Compare(m1, m2 MatrixExp, tol Tolerance) *Diff {
+	d := &Diff{Tol: tol}
+	r1, c1 := m1.Dims()
+	r2, c2 := m2.Dims()
+	if r1 != r2 || c1 != c2 {
+		d.Dims = ErrDimMismatch{
+			R1: r1,
+			C1: c1,
+			R2: r2,
+			C2: c2,
+		}
+		return d
+	}
+	mv1 := m1.Eval()
+	mv2 := m2.Eval()
+	g1 := mv1.AsGeneral()
+	g2 := mv2.AsGeneral()
+	for i := 0; i < r1; i++ {
+		for j := 0; j < c1; j++ {
+			a := g1.Data[i*g1.Stride+j]
+			b := g2.Data[i*g2.Stride+j]
+			if err, ok := tol.compare(a, b); !ok {
+				d.Elems = append(d.Elems, Difference{
+					Row: i,
+					Col: j,
+					A:   a,
+					B:   b,
+					Err: err,
+				})
+			}
+		}
+	}
+	release(m1, mv1)
+	release(m2, mv2)
+	return d
+}
+
+// EqualsApprox determines if two matrices are equal to within a tolerance.
+func EqualsApprox(m1, m2 MatrixExp, tol Tolerance) bool {
+	return Compare(m1, m2, tol).Equal()
+}
+
+// compare returns the difference between a and b, and whether it is within
+// the tolerance.
+func (tol Tolerance) compare(a, b float64) (float64, bool) {
+	if a == b {
+		return 0, true
+	}
+	if math.IsNaN(a) || math.IsNaN(b) {
+		switch tol.NaN {
+		case NaNIgnore:
+			return 0, true
+		case NaNEqual:
+			if math.IsNaN(a) && math.IsNaN(b) {
+				return 0, true
+			}
+		}
+		return math.NaN(), false
+	}
+
+	var err float64
+	switch tol.Mode {
+	case Absolute:
+		err = math.Abs(a - b)
+	case Relative:
+		err = math.Abs(a-b) / math.Max(math.Abs(a), math.Abs(b))
+	case ULP:
+		err = float64(ulps(a, b))
+	}
+	return err, err <= tol.Tol
+}
+
+// ulps returns the number of float64 values between a and b.
+func ulps(a, b float64) uint64 {
+	ia, ib := ordered(a), ordered(b)
+	if ia < ib {
+		ia, ib = ib, ia
+	}
+	return uint64(ia) - uint64(ib)
+}
+
+// ordered maps a float64 to an integer, such that adjacent float64 values map
+// to adjacent integers, and both zeros map to zero.
+func ordered(x float64) int64 {
+	i := int64(math.Float64bits(x))
+	if i < 0 {
+		i = math.MinInt64 - i
+	}
+	return i
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEqualsApprox(t *testing.T) {
+	t.Parallel()
+	nan := math.NaN()
+	next := math.Nextafter(1, 2)
+	for _, tt := range []struct {
+		a, b float64
+		tol  Tolerance
+		want bool
+	}{
+		{a: 1, b: 1, tol: Tolerance{}, want: true},
+		{a: 1, b: 1.1, tol: Tolerance{Mode: Absolute, Tol: 0.2}, want: true},
+		{a: 1, b: 1.1, tol: Tolerance{Mode: Absolute, Tol: 0.05}, want: false},
+		{a: 1000, b: 1001, tol: Tolerance{Mode: Relative, Tol: 1e-2}, want: true},
+		{a: 1000, b: 1001, tol: Tolerance{Mode: Absolute, Tol: 1e-2}, want: false},
+		{a: 1, b: next, tol: Tolerance{Mode: ULP, Tol: 1}, want: true},
+		{a: 1, b: math.Nextafter(next, 2), tol: Tolerance{Mode: ULP, Tol: 1}, want: false},
+		{a: math.Copysign(0, -1), b: 5e-324, tol: Tolerance{Mode: ULP, Tol: 1}, want: true},
+		{a: -5e-324, b: 5e-324, tol: Tolerance{Mode: ULP, Tol: 1}, want: false},
+		{a: math.Inf(1), b: math.Inf(1), tol: Tolerance{}, want: true},
+		{a: math.Inf(1), b: math.MaxFloat64, tol: Tolerance{Mode: Relative, Tol: 1}, want: false},
+		{a: nan, b: nan, tol: Tolerance{Mode: Absolute, Tol: 1}, want: false},
+		{a: nan, b: nan, tol: Tolerance{Mode: Absolute, Tol: 1, NaN: NaNEqual}, want: true},
+		{a: nan, b: 1, tol: Tolerance{Mode: Absolute, Tol: 1, NaN: NaNEqual}, want: false},
+		{a: nan, b: 1, tol: Tolerance{Mode: Absolute, Tol: 1, NaN: NaNIgnore}, want: true},
+	} {
+		m1 := &General{zeros(1, 1)}
+		m2 := &General{zeros(1, 1)}
+		m1.Set(0, 0, tt.a)
+		m2.Set(0, 0, tt.b)
+		if v := EqualsApprox(m1, m2, tt.tol); v != tt.want {
+			t.Errorf("EqualsApprox(%v, %v, %+v) equals %v, want %v", tt.a, tt.b, tt.tol, v, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	a := wellConditioned(4)
+	tol := Tolerance{Mode: Absolute, Tol: 1e-12}
+	if d := Compare(a.Mul(a.Inv()), &General{eye(4)}, tol); !d.Equal() {
+		t.Errorf("%v * %v differs from the identity: %v", a, a.Inv(), d)
+	}
+
+	b := &General{eye(4)}
+	b.Set(1, 2, 0.5)
+	d := Compare(&General{eye(4)}, b, tol)
+	if len(d.Elems) != 1 {
+		t.Fatalf("Compare found %v, want one difference", d)
+	}
+	want := Difference{Row: 1, Col: 2, A: 0, B: 0.5, Err: 0.5}
+	if d.Elems[0] != want {
+		t.Errorf("Compare found %+v, want %+v", d.Elems[0], want)
+	}
+
+	if d := Compare(a, &General{zeros(4, 1)}, tol); d.Dims == nil || d.Equal() {
+		t.Errorf("Compare found %v, want a dimension mismatch", d)
+	}
+}
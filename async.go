@@ -4,6 +4,8 @@
 
 package matrixexp
 
+import "context"
+
 // Async represents a matrix expression that evaluates into a Future.
 type Async struct {
 	M MatrixExp
@@ -27,7 +29,30 @@ func (m1 *Async) At(r, c int) float64 {
 
 // Eval returns a matrix literal.
 func (m1 *Async) Eval() MatrixLiteral {
-	return NewFuture(m1.M)
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Async) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Async) evalCtx(ctx context.Context) MatrixLiteral {
+	return NewFutureContext(ctx, m1.M)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.  The caller has to wait for the result,
+// so there is nothing to gain from evaluating it asynchronously.
+func (m1 *Async) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *Async) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	return evalIntoCtx(ctx, m1.M, dst)
 }
 
 // Copy creates a (deep) copy of the Matrix Expression.
@@ -94,3 +119,8 @@ func (m1 *Async) DivElem(m2 MatrixExp) MatrixExp {
 		Right: m2,
 	}
 }
+
+// Inv inverts a matrix.
+func (m1 *Async) Inv() MatrixExp {
+	return &Inv{m1}
+}
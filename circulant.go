@@ -0,0 +1,179 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"fmt"
+	"github.com/gonum/blas/blas64"
+)
+
+// Circulant represents a square matrix where each column is the previous
+// column rotated down by one element.  It is defined by its first column.
+type Circulant struct {
+	C []float64
+}
+
+// String implements the Stringer interface.
+func (m1 *Circulant) String() string {
+	return fmt.Sprintf("Circulant{%v}", m1.C)
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Circulant) Dims() (r, c int) {
+	r, c = len(m1.C), len(m1.C)
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Circulant) At(r, c int) float64 {
+	n := len(m1.C)
+	return m1.C[(r-c+n)%n]
+}
+
+// Eval returns a matrix literal.
+func (m1 *Circulant) Eval() MatrixLiteral {
+	n := len(m1.C)
+	m := blas64.General{
+		Rows:   n,
+		Cols:   n,
+		Stride: n,
+		Data:   getAllocator().Get(n * n),
+	}
+	for i := 0; i < n; i++ {
+		row := m.Data[i*n : (i+1)*n]
+		for j := range row {
+			row[j] = m1.C[(i-j+n)%n]
+		}
+	}
+	return &General{m}
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Circulant) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Circulant) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// leftMul multiplies the circulant matrix by a matrix literal.  Each column
+// of the product is a cyclic convolution with C, which is found with the FFT.
+func (m1 *Circulant) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	n := len(m1.C)
+	x := m2.AsGeneral()
+	m := blas64.General{
+		Rows:   n,
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(n * x.Cols),
+	}
+	if n == 0 {
+		return &General{m}
+	}
+
+	cv := newConvolver(m1.C, n)
+	for j := 0; j < x.Cols; j++ {
+		check(ctx)
+		y := cv.convolve(column(x, j))
+		// Wrap the linear convolution around to make it cyclic.
+		for i := 0; i < n; i++ {
+			v := y[i]
+			if i+n < len(y) {
+				v += y[i+n]
+			}
+			m.Data[i*m.Stride+j] = v
+		}
+	}
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Circulant) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Circulant) Copy() MatrixExp {
+	return &Circulant{
+		C: scaleCopy(1, m1.C),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix
+// expression.  Any column defines a circulant matrix, so it is always valid.
+func (m1 *Circulant) Err() error {
+	return nil
+}
+
+// T transposes a matrix.  The transpose of a circulant matrix is circulant.
+func (m1 *Circulant) T() MatrixExp {
+	n := len(m1.C)
+	c := make([]float64, n)
+	for i := range c {
+		c[i] = m1.C[(n-i)%n]
+	}
+	return &Circulant{c}
+}
+
+// Add two matrices together.  The sum of two circulant matrices is circulant.
+func (m1 *Circulant) Add(m2 MatrixExp) MatrixExp {
+	if c, ok := m2.(*Circulant); ok && len(c.C) == len(m1.C) {
+		return &Circulant{addCopy(m1.C, 1, c.C)}
+	}
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.  The difference of two
+// circulant matrices is circulant.
+func (m1 *Circulant) Sub(m2 MatrixExp) MatrixExp {
+	if c, ok := m2.(*Circulant); ok && len(c.C) == len(m1.C) {
+		return &Circulant{addCopy(m1.C, -1, c.C)}
+	}
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Circulant) Scale(c float64) MatrixExp {
+	return &Circulant{scaleCopy(c, m1.C)}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Circulant) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Circulant) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Circulant) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Circulant) Inv() MatrixExp {
+	return &Inv{m1}
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import "context"
+
+// Evaluation under a context.  Expressions with subexpressions implement
+// ctxEvaler, which evaluates them with the context threaded through to their
+// subexpressions, and check the context before evaluating each subexpression
+// and periodically inside of long running kernels.  When the context is done,
+// the evaluation is unwound with a panic of an abort, which EvalContext
+// recovers and returns as an error.  Any intermediate results that were in use
+// are left to the garbage collector.  Each top-level evaluation also carries
+// the results of its memos in its context, so that they are never shared with
+// another evaluation.
+
+// ctxEvaler is implemented by expressions that can be evaluated under a
+// context.
+type ctxEvaler interface {
+	MatrixExp
+	evalCtx(ctx context.Context) MatrixLiteral
+}
+
+// ctxEvalIntoer is implemented by expressions that can be evaluated into an
+// existing matrix literal under a context.
+type ctxEvalIntoer interface {
+	evalIntoCtx(ctx context.Context, dst MatrixLiteral) error
+}
+
+// background returns the context of a new top-level evaluation, for Eval and
+// EvalInto.
+func background() context.Context {
+	return newEvaluation(context.Background())
+}
+
+// abort unwinds an evaluation which can't be completed.
+type abort struct {
+	err error
+}
+
+// check aborts the evaluation if ctx is done.
+func check(ctx context.Context) {
+	if err := ctx.Err(); err != nil {
+		panic(abort{err})
+	}
+}
+
+// evalCtx evaluates m as part of an evaluation under ctx.
+func evalCtx(ctx context.Context, m MatrixExp) MatrixLiteral {
+	check(ctx)
+	var result MatrixLiteral
+	if e, ok := m.(ctxEvaler); ok {
+		result = e.evalCtx(ctx)
+	} else {
+		var err error
+		if result, err = m.EvalContext(ctx); err != nil {
+			panic(abort{err})
+		}
+	}
+	if f, ok := result.(*Future); ok {
+		// Waiting for the future is part of the evaluation.
+		return f.within(ctx)
+	}
+	return result
+}
+
+// evalIntoCtx evaluates m into dst as part of an evaluation under ctx.
+func evalIntoCtx(ctx context.Context, m MatrixExp, dst MatrixLiteral) error {
+	check(ctx)
+	if e, ok := m.(ctxEvalIntoer); ok {
+		return e.evalIntoCtx(ctx, dst)
+	}
+	return evalInto(ctx, m, dst)
+}
+
+// evalContext evaluates m under ctx, and returns the error which stopped the
+// evaluation, if any.
+func evalContext(ctx context.Context, m MatrixExp) (result MatrixLiteral, err error) {
+	ctx = withMemos(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(abort)
+			if !ok {
+				panic(r)
+			}
+			result, err = nil, a.err
+		}
+	}()
+	return detach(evalCtx(ctx, m)), nil
+}
+
+// evalLiteral evaluates an expression without subexpressions under ctx.
+func evalLiteral(ctx context.Context, m MatrixExp) (MatrixLiteral, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Eval(), nil
+}
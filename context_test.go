@@ -0,0 +1,85 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEvalContext(t *testing.T) {
+	t.Parallel()
+	a := wellConditioned(4)
+	m := a.Mul(a.Inv()).Add(a).Scale(2)
+	got, err := m.EvalContext(context.Background())
+	if err != nil {
+		t.Fatalf("%v.EvalContext returned error %v", m, err)
+	}
+	if want := m.Eval(); !Equals(got, want) {
+		t.Errorf("%v.EvalContext equals %v, want %v", m, got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, m := range []MatrixExp{a, m, &Async{m}, &Pow{M: a, K: 3}} {
+		if got, err := m.EvalContext(ctx); got != nil || err != context.Canceled {
+			t.Errorf("%v.EvalContext equals %v, %v, want nil, %v", m, got, err, context.Canceled)
+		}
+	}
+}
+
+func TestEvalContextDeadline(t *testing.T) {
+	t.Parallel()
+	// Takes much longer than the deadline, which is checked between the
+	// squarings.
+	a := &General{rnd(200, 200)}
+	m := &Pow{M: a, K: 1 << 30}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if got, err := m.EvalContext(ctx); got != nil || err != context.DeadlineExceeded {
+		t.Errorf("%v.EvalContext equals %v, %v, want nil, %v", m, got, err, context.DeadlineExceeded)
+	}
+}
+
+func TestFutureContext(t *testing.T) {
+	t.Parallel()
+	a := &General{rnd(200, 200)}
+	ctx, cancel := context.WithCancel(context.Background())
+	f := NewFutureContext(ctx, &Pow{M: a, K: 1 << 30})
+	cancel()
+	if err := f.Err(); err != context.Canceled {
+		t.Errorf("%v.Err() equals %v, want %v", f, err, context.Canceled)
+	}
+
+	f = NewFutureContext(context.Background(), a.Scale(2))
+	if err := f.Err(); err != nil {
+		t.Errorf("%v.Err() equals %v, want nil", f, err)
+	}
+}
+
+func TestEvalContextAsync(t *testing.T) {
+	t.Parallel()
+	// The evaluation is cancelled while it waits for the Async operand, which
+	// is held until the end of the test.
+	a := &General{rnd(3, 3)}
+	held := make(heldScheduler)
+	defer close(held)
+	ctx, cancel := context.WithCancel(WithScheduler(context.Background(), held))
+	m := &Add{Left: &Async{a.Mul(a)}, Right: a}
+	go cancel()
+	if got, err := m.EvalContext(ctx); got != nil || err != context.Canceled {
+		t.Errorf("%v.EvalContext equals %v, %v, want nil, %v", m, got, err, context.Canceled)
+	}
+
+	// A failed Async operand fails the evaluation.
+	b := &General{rnd(2, 3)}
+	m = &Add{Left: &Async{&Mul{Left: b, Right: b}}, Right: b}
+	if _, err := m.EvalContext(context.Background()); err == nil {
+		t.Errorf("%v.EvalContext returned nil error, want an ErrPanic", m)
+	} else if _, ok := err.(*ErrPanic); !ok {
+		t.Errorf("%v.EvalContext returned error %v, want an ErrPanic", m, err)
+	}
+}
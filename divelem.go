@@ -4,9 +4,7 @@
 
 package matrixexp
 
-import (
-	"github.com/gonum/blas/blas64"
-)
+import "context"
 
 // DivElem represents element-wise division.
 type DivElem struct {
@@ -32,25 +30,48 @@ func (m1 *DivElem) At(r, c int) float64 {
 
 // Eval returns a matrix literal.
 func (m1 *DivElem) Eval() MatrixLiteral {
-	r, c := m1.Dims()
+	return m1.evalCtx(background())
+}
 
-	lm := m1.Left.Eval()
-	rm := m1.Right.Eval()
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *DivElem) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
 
-	v1 := lm.AsVector()
-	v2 := rm.AsVector()
-	for i, v := range v2 {
-		v1[i] /= v
-	}
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *DivElem) evalCtx(ctx context.Context) MatrixLiteral {
+	return fuseEval(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *DivElem) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *DivElem) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	return fuse(ctx, m1, dst)
+}
+
+// operands returns the operands of the element-wise expression.
+func (m1 *DivElem) operands() []MatrixExp {
+	return []MatrixExp{m1.Left, m1.Right}
+}
 
-	return &General{blas64.General{
-		Rows:   r,
-		Cols:   c,
-		Stride: c,
-		Data:   v1,
-	}}
+// combine computes a row of the element-wise expression.
+func (m1 *DivElem) combine(dst []float64, args [][]float64) {
+	v1, v2 := args[0], args[1]
+	for j, v := range v2 {
+		dst[j] = v1[j] / v
+	}
 }
 
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *DivElem) ownedResult() {}
+
 // Copy creates a (deep) copy of the Matrix Expression.
 func (m1 *DivElem) Copy() MatrixExp {
 	return &DivElem{
@@ -133,3 +154,8 @@ func (m1 *DivElem) DivElem(m2 MatrixExp) MatrixExp {
 		Right: m2,
 	}
 }
+
+// Inv inverts a matrix.
+func (m1 *DivElem) Inv() MatrixExp {
+	return &Inv{m1}
+}
@@ -69,3 +69,83 @@ type ErrInnerDimMismatch struct {
 func (e ErrInnerDimMismatch) Error() string {
 	return fmt.Sprintf("inner dimension mismatch: %d vs %d", e.C, e.R)
 }
+
+// ErrNotSquare happens when an operation that is only defined for square
+// matrices, such as inversion or exponentiation, is given a matrix with a
+// different number of rows and columns.
+type ErrNotSquare struct {
+	R, C int
+}
+
+func (e ErrNotSquare) Error() string {
+	return fmt.Sprintf("matrix is not square: (%d, %d)", e.R, e.C)
+}
+
+// ErrNotVector happens when an operation that is only defined for column
+// vectors, such as an outer product, is given a matrix with more than one
+// column.
+type ErrNotVector struct {
+	R, C int
+}
+
+func (e ErrNotVector) Error() string {
+	return fmt.Sprintf("matrix is not a column vector: (%d, %d)", e.R, e.C)
+}
+
+// ErrInvalidPermutation happens when the indices of a permutation are out of
+// range or repeated.
+type ErrInvalidPermutation struct {
+	I, Index int
+}
+
+func (e ErrInvalidPermutation) Error() string {
+	return fmt.Sprintf("invalid permutation: index %d at position %d is out of range or repeated", e.Index, e.I)
+}
+
+// ErrSharedElement happens when the first column and row of a Toeplitz matrix,
+// or the first column and last row of a Hankel matrix, don't agree on the
+// element that they share, or when only one of them is empty.  Col and Row are
+// the lengths of the column and row.
+type ErrSharedElement struct {
+	Col, Row int
+	CV, RV   float64 // the shared element in the column and the row
+}
+
+func (e ErrSharedElement) Error() string {
+	if e.Col == 0 || e.Row == 0 {
+		return fmt.Sprintf("column of length %d and row of length %d don't share an element", e.Col, e.Row)
+	}
+	return fmt.Sprintf("shared element mismatch: %v in column vs %v in row", e.CV, e.RV)
+}
+
+// ErrPanic happens when the evaluation of a Future panics.  Value is the value
+// that was passed to panic, and Stack is the stack trace of the goroutine that
+// panicked.  It is always used as a pointer, so that it can be compared.
+type ErrPanic struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *ErrPanic) Error() string {
+	return fmt.Sprintf("evaluation panicked: %v", e.Value)
+}
+
+// ErrUnbound happens when a placeholder is used without being bound to a
+// matrix.
+type ErrUnbound string
+
+func (e ErrUnbound) Error() string {
+	return fmt.Sprintf("unbound placeholder: %q", string(e))
+}
+
+// ErrBindingDims happens when a placeholder is bound to a matrix with
+// different dimensions than the placeholder.
+type ErrBindingDims struct {
+	Name   string
+	R, C   int
+	BR, BC int
+}
+
+func (e ErrBindingDims) Error() string {
+	return fmt.Sprintf("placeholder %q is (%d, %d) but is bound to (%d, %d)", e.Name, e.R, e.C, e.BR, e.BC)
+}
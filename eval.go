@@ -0,0 +1,101 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas/blas64"
+)
+
+// Helpers for evaluating matrix expressions into existing matrix literals.
+// The destination of EvalInto may also be used as an operand of the
+// expression being evaluated, so anything that reads from an operand while
+// writing to the destination has to be careful about aliasing.
+
+// newGeneral returns a new r x c General filled with zeros.
+func newGeneral(r, c int) *General {
+	return &General{blas64.General{
+		Rows:   r,
+		Cols:   c,
+		Stride: c,
+		Data:   getAllocator().Get(r * c),
+	}}
+}
+
+// checkDst returns an error if dst can't be used to store the result of m.
+func checkDst(m MatrixExp, dst MatrixLiteral) error {
+	if err := dst.Err(); err != nil {
+		return err
+	}
+	r1, c1 := m.Dims()
+	r2, c2 := dst.Dims()
+	if r1 != r2 || c1 != c2 {
+		return ErrDimMismatch{
+			R1: r1,
+			C1: c1,
+			R2: r2,
+			C2: c2,
+		}
+	}
+	return nil
+}
+
+// sameArray determines if two slices share a backing array.  Slices made by
+// reslicing the same array share the last element of their capacity.
+func sameArray(a, b []float64) bool {
+	if cap(a) == 0 || cap(b) == 0 {
+		return false
+	}
+	return &a[:cap(a)][cap(a)-1] == &b[:cap(b)][cap(b)-1]
+}
+
+// sameLayout determines if two matrices refer to exactly the same elements.
+func sameLayout(a, b blas64.General) bool {
+	if len(a.Data) == 0 || len(b.Data) == 0 {
+		return len(a.Data) == len(b.Data)
+	}
+	return &a.Data[0] == &b.Data[0] && a.Stride == b.Stride
+}
+
+// elemOperand returns an operand that can be read element by element while the
+// same elements of dst are written.  That is a itself, unless a shares memory
+// with dst in some other layout, in which case it is a copy of a.
+func elemOperand(dst, a blas64.General) blas64.General {
+	if sameArray(dst.Data, a.Data) && !sameLayout(dst, a) {
+		return copyGeneral(a)
+	}
+	return a
+}
+
+// disjoint returns a, or a copy of a if it shares any memory with dst.
+func disjoint(dst, a blas64.General) blas64.General {
+	if sameArray(dst.Data, a.Data) {
+		return copyGeneral(a)
+	}
+	return a
+}
+
+// copyInto copies src into dst, which must have the same dimensions.
+func copyInto(dst, src blas64.General) {
+	if sameLayout(dst, src) {
+		return
+	}
+	src = disjoint(dst, src)
+	for i := 0; i < src.Rows; i++ {
+		copy(dst.Data[i*dst.Stride:i*dst.Stride+dst.Cols], src.Data[i*src.Stride:i*src.Stride+src.Cols])
+	}
+}
+
+// evalInto evaluates m into dst under ctx by evaluating it and then copying the
+// result.  It is used by expressions that have no better way to write into dst.
+func evalInto(ctx context.Context, m MatrixExp, dst MatrixLiteral) error {
+	if err := checkDst(m, dst); err != nil {
+		return err
+	}
+	result := evalCtx(ctx, m)
+	copyInto(dst.AsGeneral(), result.AsGeneral())
+	release(m, result)
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"github.com/gonum/blas/blas64"
+	"testing"
+)
+
+// TestEvalIntoAlias checks that EvalInto produces the right answer when the
+// destination is also one of the operands.
+func TestEvalIntoAlias(t *testing.T) {
+	t.Parallel()
+	for ti, tt := range []struct {
+		expr func(a, b *General) MatrixExp
+	}{
+		{expr: func(a, b *General) MatrixExp { return a.Add(b) }},
+		{expr: func(a, b *General) MatrixExp { return b.Sub(a) }},
+		{expr: func(a, b *General) MatrixExp { return a.MulElem(a) }},
+		{expr: func(a, b *General) MatrixExp { return b.DivElem(a) }},
+		{expr: func(a, b *General) MatrixExp { return a.Scale(3) }},
+		{expr: func(a, b *General) MatrixExp { return a.T() }},
+		{expr: func(a, b *General) MatrixExp { return a.T().Add(a) }},
+		{expr: func(a, b *General) MatrixExp { return a.Mul(b) }},
+		{expr: func(a, b *General) MatrixExp { return b.Mul(a.T()) }},
+		{expr: func(a, b *General) MatrixExp { return &Pow{M: a, K: 3} }},
+	} {
+		a := GeneralRand(4, 4).(*General)
+		b := GeneralOnes(4, 4).Scale(2).Eval().(*General)
+		want := tt.expr(a.Copy().(*General), b.Copy().(*General)).Eval()
+		if err := tt.expr(a, b).EvalInto(a); err != nil {
+			t.Errorf("%d: EvalInto returned error %v", ti, err)
+		}
+		if !Equals(a, want) {
+			t.Errorf("%d: %v EvalInto itself equals %v, want %v", ti, tt.expr(a, b), a, want)
+		}
+	}
+}
+
+// TestEvalIntoStride checks that EvalInto respects the stride of the
+// destination, and doesn't touch elements outside of it.
+func TestEvalIntoStride(t *testing.T) {
+	t.Parallel()
+	a := GeneralRand(3, 2)
+	b := GeneralOnes(3, 2)
+	backing := &General{blas64.General{Rows: 3, Cols: 5, Stride: 5, Data: make([]float64, 15)}}
+	dst := &General{blas64.General{Rows: 3, Cols: 2, Stride: 5, Data: backing.Data[1:]}}
+	for _, m := range []MatrixExp{a.Add(b), a.Scale(2), b.T().T(), a.Mul(b.T()).Mul(a)} {
+		for i := range backing.Data {
+			backing.Data[i] = -1
+		}
+		if err := m.EvalInto(dst); err != nil {
+			t.Errorf("%v EvalInto returned error %v", m, err)
+		}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 5; j++ {
+				want := -1.0
+				if j == 1 || j == 2 {
+					want = m.At(i, j-1)
+				}
+				if got := backing.At(i, j); got != want {
+					t.Errorf("%v EvalInto strided At(%d, %d) equals %v, want %v", m, i, j, got, want)
+				}
+			}
+		}
+	}
+}
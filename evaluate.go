@@ -0,0 +1,286 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// EvalOptions controls how Evaluate evaluates a matrix expression.
+type EvalOptions struct {
+	// Context stops the evaluation early when it is done.  A nil Context is
+	// the same as context.Background().
+	Context context.Context
+
+	// Workers is the maximum number of nodes that are evaluated at the same
+	// time.  If it is less than 1, it is GOMAXPROCS.
+	Workers int
+}
+
+// NodeTiming records when a node of an expression was evaluated, and how long
+// it took.
+type NodeTiming struct {
+	Exp      MatrixExp
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Evaluate evaluates a matrix expression, running independent parts of it in
+// parallel.  The expression is split into nodes at each subexpression that
+// Eval would materialize, so that element-wise expressions are still fused and
+// structured products are still never formed, and a subexpression that is
+// used in more than one place (by pointer, or through a Memo) is only
+// evaluated once.  The operands of Async expressions are evaluated like any
+// other node, rather than as Futures.  Each node is evaluated as soon as all
+// of the nodes it depends on have been.  The result is the same as the result
+// of Eval.
+//
+// Evaluate also returns the timing of each node, in an order where each node
+// comes after the nodes it depends on.  The time spent in a subexpression that
+// is part of a larger node is included in that node.
+func Evaluate(m MatrixExp, opts EvalOptions) (MatrixLiteral, []NodeTiming, error) {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	m = unwrap(m)
+	if l, ok := m.(MatrixLiteral); ok {
+		return l, nil, nil
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	d := &dag{tasks: make(map[MatrixExp]*task)}
+	root := d.taskFor(m)
+	if err := d.run(ctx, workers); err != nil {
+		return nil, nil, err
+	}
+	timings := make([]NodeTiming, len(d.order))
+	for i, t := range d.order {
+		timings[i] = t.timing
+	}
+	return root.result, timings, nil
+}
+
+// task is a node of an expression which Evaluate evaluates on its own.
+type task struct {
+	exp     MatrixExp
+	deps    []*task
+	parents []*task
+	pending int // the number of deps which haven't been evaluated
+	uses    int // the number of parents which haven't been evaluated
+	result  MatrixLiteral
+	err     error
+	timing  NodeTiming
+}
+
+// dag is the graph of tasks in an expression.
+type dag struct {
+	tasks map[MatrixExp]*task
+	order []*task // deps before parents
+}
+
+// unwrap removes any Memo and Async expressions around m, which Evaluate
+// handles itself.
+func unwrap(m MatrixExp) MatrixExp {
+	for {
+		switch m1 := m.(type) {
+		case *Memo:
+			m = m1.M
+		case *Async:
+			m = m1.M
+		default:
+			return m
+		}
+	}
+}
+
+// taskFor returns the task which evaluates m, which must not be a literal, a
+// Memo, or an Async.
+func (d *dag) taskFor(m MatrixExp) *task {
+	if t, ok := d.tasks[m]; ok {
+		return t
+	}
+	t := &task{exp: m}
+	d.tasks[m] = t
+	d.addDeps(t, m)
+	t.pending = len(t.deps)
+	d.order = append(d.order, t)
+	return t
+}
+
+// addDeps adds the tasks that the part of t rooted at m depends on.
+func (d *dag) addDeps(t *task, m MatrixExp) {
+	forOperands(m, func(name string, child MatrixExp) {
+		if inlined(m, name, child) {
+			d.addDeps(t, child)
+			return
+		}
+		child = unwrap(child)
+		if _, ok := child.(MatrixLiteral); ok {
+			return
+		}
+		dep := d.taskFor(child)
+		t.deps = append(t.deps, dep)
+		dep.parents = append(dep.parents, t)
+		dep.uses++
+	})
+}
+
+// instantiate returns m with each of its operands replaced, either by the
+// result of the task which evaluates it, or if it is part of the same task, by
+// the instantiated operand.
+func (d *dag) instantiate(m MatrixExp) MatrixExp {
+	if !structNode(m) {
+		return m
+	}
+	r := reflect.ValueOf(m).Elem()
+	cp := reflect.New(r.Type())
+	cp.Elem().Set(r)
+	replaced := false
+	forOperands(m, func(name string, child MatrixExp) {
+		var op MatrixExp
+		if inlined(m, name, child) {
+			op = d.instantiate(child)
+		} else if l, ok := unwrap(child).(MatrixLiteral); ok {
+			op = l
+		} else {
+			op = d.tasks[unwrap(child)].result
+		}
+		cp.Elem().FieldByName(name).Set(reflect.ValueOf(op))
+		replaced = true
+	})
+	if !replaced {
+		return m
+	}
+	return cp.Interface().(MatrixExp)
+}
+
+// inlined determines if the operand of m in the named field is evaluated as
+// part of m by Eval, instead of being materialized first.
+func inlined(m MatrixExp, name string, child MatrixExp) bool {
+	switch p := m.(type) {
+	case elementwise:
+		_, ok := child.(elementwise)
+		return ok
+	case *Mul:
+		if name == "Left" {
+			_, ok := child.(leftMultiplier)
+			return ok
+		}
+		_, left := p.Left.(leftMultiplier)
+		_, right := child.(rightMultiplier)
+		return !left && right
+	}
+	return false
+}
+
+// forOperands calls fn with the name and value of each field of m that holds a
+// subexpression.
+func forOperands(m MatrixExp, fn func(name string, child MatrixExp)) {
+	if !structNode(m) {
+		return
+	}
+	r := reflect.ValueOf(m).Elem()
+	for i := 0; i < r.NumField(); i++ {
+		f := r.Field(i)
+		if r.Type().Field(i).PkgPath != "" || f.Kind() != reflect.Interface || f.IsNil() {
+			continue
+		}
+		if child, ok := f.Interface().(MatrixExp); ok {
+			fn(r.Type().Field(i).Name, child)
+		}
+	}
+}
+
+// run evaluates all of the tasks with the given number of workers.  If a task
+// fails, the tasks which haven't started are skipped, and the results of the
+// tasks which have finished are released.
+func (d *dag) run(ctx context.Context, workers int) (err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	ready := make(chan *task, len(d.order))
+	done := make(chan *task, len(d.order))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range ready {
+				if err := ctx.Err(); err != nil {
+					t.err = err
+				} else {
+					d.eval(ctx, t)
+				}
+				done <- t
+			}
+		}()
+	}
+	defer func() {
+		cancel()
+		close(ready)
+		wg.Wait()
+		if err != nil {
+			for _, t := range d.order {
+				if t.result != nil {
+					release(t.exp, t.result)
+					t.result = nil
+				}
+			}
+		}
+	}()
+
+	for _, t := range d.order {
+		if t.pending == 0 {
+			ready <- t
+		}
+	}
+	for left := len(d.order); left > 0; left-- {
+		t := <-done
+		if t.err != nil {
+			return t.err
+		}
+		for _, p := range t.parents {
+			if p.pending--; p.pending == 0 {
+				ready <- p
+			}
+		}
+		for _, dep := range t.deps {
+			if dep.uses--; dep.uses == 0 {
+				release(dep.exp, dep.result)
+				dep.result = nil
+			}
+		}
+	}
+	return nil
+}
+
+// eval evaluates a single task, whose deps have all been evaluated.
+func (d *dag) eval(ctx context.Context, t *task) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.err = &ErrPanic{
+				Value: r,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+	start := time.Now()
+	t.result, t.err = evalContext(ctx, d.instantiate(t.exp))
+	t.timing = NodeTiming{
+		Exp:      t.exp,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+}
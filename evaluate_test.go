@@ -0,0 +1,86 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+	a := wellConditioned(4)
+	b := &General{rnd(2, 2)}
+	s := a.Mul(a.T()).Add(a)
+	memo := NewMemo(a.Inv(), 2)
+	k := &Kron{Left: b, Right: b}
+	cases := []struct {
+		m     MatrixExp
+		nodes int
+	}{
+		{a, 0},
+		{a.Add(a).Scale(2), 1},
+		{s.Sub(s).MulElem(a.Mul(a)), 4},
+		{memo.Add(memo).Mul(a), 3},
+		{k.Mul(a).Add(a.Mul(k)), 4},
+		{(&Async{a.Mul(a)}).Add(a.T()), 3},
+		{&Pow{M: s.Add(a), K: 3}, 4},
+	}
+	for _, c := range cases {
+		for _, workers := range []int{0, 1, 3} {
+			got, timings, err := Evaluate(c.m, EvalOptions{Workers: workers})
+			if err != nil {
+				t.Fatalf("Evaluate(%v) returned error %v", c.m, err)
+			}
+			if want := c.m.Eval(); !Equals(got, want) {
+				t.Errorf("Evaluate(%v) equals %v, want %v", c.m, got, want)
+			}
+			if len(timings) != c.nodes {
+				t.Errorf("Evaluate(%v) timed %v nodes, want %v", c.m, len(timings), c.nodes)
+			}
+			if c.nodes > 0 && timings[len(timings)-1].Exp != c.m {
+				t.Errorf("Evaluate(%v) last timed %v, want %v", c.m, timings[len(timings)-1].Exp, c.m)
+			}
+		}
+	}
+}
+
+func TestEvaluateContext(t *testing.T) {
+	t.Parallel()
+	a := &General{rnd(200, 200)}
+	m := (&Pow{M: a, K: 1 << 30}).Add(a.Mul(a))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, _, err := Evaluate(m, EvalOptions{Context: ctx}); got != nil || err != context.Canceled {
+		t.Errorf("Evaluate(%v) equals %v, %v, want nil, %v", m, got, err, context.Canceled)
+	}
+
+	m2 := a.Add(a).Mul(&General{rnd(3, 3)})
+	if _, _, err := Evaluate(m2, EvalOptions{}); err == nil {
+		t.Errorf("Evaluate(%v) returned nil error, want an ErrPanic", m2)
+	}
+}
+
+// blocking is an expression whose evaluation doesn't finish until it is
+// cancelled.
+type blocking struct {
+	MatrixExp
+}
+
+func (m1 *blocking) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestEvaluateFailure(t *testing.T) {
+	t.Parallel()
+	// The failing node stops the one that is still running.
+	a := &General{rnd(3, 3)}
+	bad := a.Add(a).Mul(&General{rnd(2, 2)})
+	m := &Add{Left: &blocking{a}, Right: bad}
+	if got, _, err := Evaluate(m, EvalOptions{Workers: 2}); got != nil || err == nil {
+		t.Errorf("Evaluate(%v) equals %v, %v, want nil and an ErrPanic", m, got, err)
+	}
+}
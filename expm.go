@@ -0,0 +1,197 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas/blas64"
+	"math"
+)
+
+// expmPade is the degree of the diagonal Padé approximant used by Expm.
+const expmPade = 6
+
+// Expm represents the matrix exponential of a square matrix.
+type Expm struct {
+	M MatrixExp
+}
+
+// String implements the Stringer interface.
+func (m1 *Expm) String() string {
+	return "Expm{" + m1.M.String() + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Expm) Dims() (r, c int) {
+	r, c = m1.M.Dims()
+	return
+}
+
+// At returns the value at a given row, column index.  This evaluates the
+// whole exponential.
+func (m1 *Expm) At(r, c int) float64 {
+	return m1.Eval().At(r, c)
+}
+
+// Eval returns a matrix literal.  It uses scaling and squaring with a Padé
+// approximation, as in Golub & Van Loan, Matrix Computations, Algorithm 11.3.1.
+func (m1 *Expm) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Expm) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Expm) evalCtx(ctx context.Context) MatrixLiteral {
+	n, _ := m1.M.Dims()
+	a := writable(m1.M, evalCtx(ctx, m1.M))
+
+	// Scale a so that its infinity norm is at most 1/2.
+	var norm float64
+	for i := 0; i < n; i++ {
+		var s float64
+		for _, v := range a.Data[i*n : (i+1)*n] {
+			s += math.Abs(v)
+		}
+		norm = math.Max(norm, s)
+	}
+	j := 0
+	if norm > 0 {
+		if _, e := math.Frexp(norm); e+1 > 0 {
+			j = e + 1
+		}
+	}
+	blas64.Scal(n*n, math.Ldexp(1, -j), blas64.Vector{Inc: 1, Data: a.Data})
+
+	// Padé approximation of exp(a) = d^-1 * p
+	alloc := getAllocator()
+	x := identity(n)
+	p := identity(n)
+	d := identity(n)
+	c := 1.0
+	for k := 1; k <= expmPade; k++ {
+		check(ctx)
+		c *= float64(expmPade-k+1) / float64((2*expmPade-k+1)*k)
+		ax := gemm(a, x)
+		alloc.Put(x.Data)
+		x = ax
+		blas64.Axpy(n*n, c, blas64.Vector{Inc: 1, Data: x.Data}, blas64.Vector{Inc: 1, Data: p.Data})
+		if k%2 == 0 {
+			blas64.Axpy(n*n, c, blas64.Vector{Inc: 1, Data: x.Data}, blas64.Vector{Inc: 1, Data: d.Data})
+		} else {
+			blas64.Axpy(n*n, -c, blas64.Vector{Inc: 1, Data: x.Data}, blas64.Vector{Inc: 1, Data: d.Data})
+		}
+	}
+	dinv := invert(ctx, d.Data, n)
+	alloc.Put(d.Data)
+	d.Data = dinv
+	f := gemm(d, p)
+	alloc.Put(a.Data)
+	alloc.Put(x.Data)
+	alloc.Put(p.Data)
+	alloc.Put(d.Data)
+
+	// Undo the scaling by repeated squaring.
+	for ; j > 0; j-- {
+		check(ctx)
+		ff := gemm(f, f)
+		alloc.Put(f.Data)
+		f = ff
+	}
+	return &General{f}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Expm) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Expm) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Expm) Copy() MatrixExp {
+	return &Expm{
+		M: m1.M.Copy(),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Expm) Err() error {
+	if err := m1.M.Err(); err != nil {
+		return err
+	}
+	if r, c := m1.M.Dims(); r != c {
+		return ErrNotSquare{
+			R: r,
+			C: c,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *Expm) T() MatrixExp {
+	return &T{m1}
+}
+
+// Add two matrices together.
+func (m1 *Expm) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Expm) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Expm) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Expm) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Expm) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Expm) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.  The inverse of exp(A) is exp(-A).
+func (m1 *Expm) Inv() MatrixExp {
+	return &Expm{m1.M.Scale(-1)}
+}
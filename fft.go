@@ -0,0 +1,99 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft computes the discrete Fourier transform of a in place, using the
+// iterative radix 2 Cooley-Tukey algorithm.  The length of a must be a power
+// of two.  If inverse is true, it computes the unscaled inverse transform.
+func fft(a []complex128, inverse bool) {
+	n := len(a)
+
+	// Permute into bit reversed order.
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	w := make([]complex128, n/2)
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		theta := 2 * math.Pi / float64(size)
+		if !inverse {
+			theta = -theta
+		}
+		for k := range w[:half] {
+			w[k] = cmplx.Rect(1, theta*float64(k))
+		}
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				u := a[start+k]
+				v := a[start+k+half] * w[k]
+				a[start+k] = u + v
+				a[start+k+half] = u - v
+			}
+		}
+	}
+}
+
+// A convolver computes linear convolutions of a fixed sequence with other
+// sequences of up to a given length, reusing the transform of the fixed
+// sequence.
+type convolver struct {
+	n    int          // length of the fixed sequence
+	fa   []complex128 // transform of the fixed sequence
+	work []complex128
+}
+
+// newConvolver prepares to convolve a with sequences of length up to nb.
+func newConvolver(a []float64, nb int) *convolver {
+	size := 1
+	for size < len(a)+nb-1 {
+		size <<= 1
+	}
+	fa := make([]complex128, size)
+	for i, v := range a {
+		fa[i] = complex(v, 0)
+	}
+	fft(fa, false)
+	return &convolver{
+		n:    len(a),
+		fa:   fa,
+		work: make([]complex128, size),
+	}
+}
+
+// convolve returns the linear convolution of the fixed sequence with b, which
+// has length len(a)+len(b)-1.
+func (cv *convolver) convolve(b []float64) []float64 {
+	w := cv.work
+	for i := range w {
+		w[i] = 0
+	}
+	for i, v := range b {
+		w[i] = complex(v, 0)
+	}
+	fft(w, false)
+	for i, v := range cv.fa {
+		w[i] *= v
+	}
+	fft(w, true)
+	scale := 1 / float64(len(w))
+	c := make([]float64, cv.n+len(b)-1)
+	for i := range c {
+		c[i] = real(w[i]) * scale
+	}
+	return c
+}
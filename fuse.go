@@ -0,0 +1,178 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas/blas64"
+)
+
+// Loop fusion for element-wise expressions.  A tree of element-wise nodes like
+// x.Add(y).MulElem(z).Scale(2) is evaluated in a single pass over the output,
+// one row of a tile at a time, so that none of the interior nodes are
+// materialized.  Only the leaves of the tree, which are the operands that are
+// not element-wise (literals, products, and so on) are evaluated in full.  The
+// tiles are evaluated in parallel (see Tiling), each by its own copy of the
+// kernels.
+
+// elementwise is implemented by expressions where each element of the result
+// depends only on the same element of each of the operands.
+type elementwise interface {
+	MatrixExp
+
+	// operands returns the operands of the expression.
+	operands() []MatrixExp
+
+	// combine sets each element of dst from the same element of each of the
+	// operands, which are given in the same order as operands returns them.
+	combine(dst []float64, args [][]float64)
+}
+
+// kernel is a node of a fused element-wise expression.
+type kernel struct {
+	op   elementwise // op is nil for a leaf
+	args []*kernel
+	vals [][]float64 // a row of each of the args
+	row  []float64   // scratch space for a row of a tile of the result
+
+	leaf blas64.General
+}
+
+// fusion holds the state of the evaluation of a fused expression.
+type fusion struct {
+	ctx     context.Context
+	leaves  []*kernel
+	exps    []MatrixExp
+	results []MatrixLiteral
+	scratch [][]float64
+}
+
+// build creates the kernel for m, evaluating any leaves.
+func (f *fusion) build(m MatrixExp) *kernel {
+	e, ok := m.(elementwise)
+	if !ok {
+		k := &kernel{}
+		f.leaves = append(f.leaves, k)
+		f.exps = append(f.exps, m)
+		f.results = append(f.results, evalCtx(f.ctx, m))
+		return k
+	}
+	ops := e.operands()
+	k := &kernel{
+		op:   e,
+		args: make([]*kernel, len(ops)),
+		vals: make([][]float64, len(ops)),
+	}
+	for i, o := range ops {
+		k.args[i] = f.build(o)
+	}
+	return k
+}
+
+// at returns columns [j0, j1) of row i of the kernel.
+func (k *kernel) at(i, j0, j1 int) []float64 {
+	if k.op == nil {
+		return k.leaf.Data[i*k.leaf.Stride+j0 : i*k.leaf.Stride+j1]
+	}
+	for j, a := range k.args {
+		k.vals[j] = a.at(i, j0, j1)
+	}
+	row := k.row[:j1-j0]
+	k.op.combine(row, k.vals)
+	return row
+}
+
+// clone copies the kernel tree below k, so that the copy can be evaluated at
+// the same time as the original.  The leaves are shared.
+func (k *kernel) clone() *kernel {
+	if k.op == nil {
+		return k
+	}
+	c := &kernel{
+		op:   k.op,
+		args: make([]*kernel, len(k.args)),
+		vals: make([][]float64, len(k.vals)),
+	}
+	for i, a := range k.args {
+		c.args[i] = a.clone()
+	}
+	return c
+}
+
+// allocRows gives every interior kernel below k, but not k itself, a scratch
+// row of c elements.
+func (f *fusion) allocRows(k *kernel, c int) {
+	for _, a := range k.args {
+		if a.op != nil {
+			a.row = getAllocator().Get(c)
+			f.scratch = append(f.scratch, a.row)
+			f.allocRows(a, c)
+		}
+	}
+}
+
+// run evaluates the kernel into dst one tile at a time, and then releases the
+// leaves.  A leaf may be dst itself, because each element of a leaf is read
+// before the same element of dst is written.
+func (f *fusion) run(root *kernel, dst blas64.General) {
+	for i, k := range f.leaves {
+		k.leaf = elemOperand(dst, f.results[i].AsGeneral())
+	}
+	workers := 0
+	forTiles(f.ctx, dst.Rows, dst.Cols, func(width int) func(i0, i1, j0, j1 int) {
+		k := root
+		if workers > 0 {
+			k = root.clone()
+		}
+		workers++
+		f.allocRows(k, width)
+		return func(i0, i1, j0, j1 int) {
+			for i := i0; i < i1; i++ {
+				k.row = dst.Data[i*dst.Stride+j0 : i*dst.Stride+j1]
+				k.at(i, j0, j1)
+			}
+		}
+	})
+
+	alloc := getAllocator()
+	for _, s := range f.scratch {
+		alloc.Put(s)
+	}
+	for i, m := range f.exps {
+		if m != nil {
+			release(m, f.results[i])
+		}
+	}
+}
+
+// fuse evaluates the element-wise expression m into dst in a single pass under
+// ctx.
+func fuse(ctx context.Context, m elementwise, dst MatrixLiteral) error {
+	if err := checkDst(m, dst); err != nil {
+		return err
+	}
+	f := &fusion{ctx: ctx}
+	f.run(f.build(m), dst.AsGeneral())
+	return nil
+}
+
+// fuseEval evaluates the element-wise expression m in a single pass under ctx.
+// If one of the leaves is a temporary with the same dimensions as m, the result
+// is written over it instead of into a new matrix.
+func fuseEval(ctx context.Context, m elementwise) MatrixLiteral {
+	f := &fusion{ctx: ctx}
+	root := f.build(m)
+	r, c := m.Dims()
+	for i, l := range f.exps {
+		if g, ok := temporary(l, f.results[i]); ok && g.Rows == r && g.Cols == c {
+			f.exps[i] = nil
+			f.run(root, g)
+			return f.results[i]
+		}
+	}
+	d := newGeneral(r, c)
+	f.run(root, d.General)
+	return d
+}
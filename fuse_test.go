@@ -0,0 +1,64 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import "testing"
+
+func TestFuse(t *testing.T) {
+	t.Parallel()
+	x := &General{rnd(5, 4)}
+	y := &General{rnd(5, 4)}
+	z := &General{rnd(5, 4)}
+	w := &General{rnd(5, 4)}
+	m := x.Add(y).MulElem(z).Scale(2).Sub(w).DivElem(z.Add(w))
+	got := m.Eval()
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 4; j++ {
+			want := ((x.At(i, j)+y.At(i, j))*z.At(i, j)*2 - w.At(i, j)) / (z.At(i, j) + w.At(i, j))
+			if v := got.At(i, j); v != want {
+				t.Errorf("%v.At(%v, %v) equals %v, want %v", m, i, j, v, want)
+			}
+		}
+	}
+}
+
+func TestFuseAlloc(t *testing.T) {
+	// This replaces the package allocator, so it can't run in parallel.
+	a := &checkAllocator{out: make(map[*float64]bool)}
+	SetAllocator(a)
+	defer SetAllocator(NewPool())
+
+	x := &General{rnd(6, 5)}
+	y := &General{rnd(5, 5)}
+	for _, tt := range []struct {
+		m    MatrixExp
+		want []int
+	}{
+		// only the result is materialized, along with a row for each
+		// interior node.
+		{m: x.Add(x).MulElem(x).Scale(2).Sub(x), want: []int{30, 5, 5, 5}},
+		// the product is a leaf, and is materialized, and then
+		// overwritten with the result.
+		{m: x.Mul(y).Add(x).Scale(2), want: []int{30, 5}},
+	} {
+		a.gets = nil
+		tt.m.Eval()
+		if !equalInts(a.gets, tt.want) {
+			t.Errorf("%v allocated %v, want %v", tt.m, a.gets, tt.want)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
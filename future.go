@@ -5,42 +5,214 @@
 package matrixexp
 
 import (
+	"context"
 	"github.com/gonum/blas/blas64"
+	"runtime/debug"
 	"strconv"
 )
 
 // NewFuture constructs a Future MatrixLiteral from a Matrix Expression and
 // then begins evaluating it.
 func NewFuture(M MatrixExp) *Future {
+	return NewFutureContext(context.Background(), M)
+}
+
+// NewFutureContext constructs a Future MatrixLiteral from a Matrix Expression
+// and then begins evaluating it under a context, with the context's Scheduler
+// (see WithScheduler and SetScheduler).  If the context is done before
+// the evaluation finishes, the evaluation stops, and the Future's Err method
+// returns the context's error.  If the evaluation panics, the panic is
+// recovered and Err returns an ErrPanic.
+//
+// Once a Future has failed, its At, Set, AsVector, and AsGeneral methods panic
+// with the error returned by Err, and EvalInto returns it.  When a Future is the
+// operand of an expression, its failure is returned by EvalContext instead.
+func NewFutureContext(ctx context.Context, M MatrixExp) *Future {
 	ch := make(chan struct{})
 	r, c := M.Dims()
-	F := &Future{
-		r:  r,
-		c:  c,
-		ch: ch,
-		m:  nil,
-	}
-	go func(M MatrixExp, F *Future, ch chan<- struct{}) {
-		F.m = M.Eval()
-		close(ch)
-	}(M, F, ch)
+	F := newFuture(ctx)
+	F.r, F.c = r, c
+	F.ch = ch
+	evalFuture(F, ch, func() MatrixExp { return M })
 	return F
 }
 
+// evalFuture evaluates the expression returned by M into F with the Scheduler
+// of F's context, and then closes ch.
+func evalFuture(F *Future, ch chan<- struct{}, M func() MatrixExp) {
+	getScheduler(F.ctx).Go(func() {
+		defer close(ch)
+		defer func() {
+			if r := recover(); r != nil {
+				F.m, F.err = nil, &ErrPanic{
+					Value: r,
+					Stack: debug.Stack(),
+				}
+			}
+		}()
+		F.m, F.err = evalContext(F.ctx, M())
+	})
+}
+
 // Future is a matrix literal that is asynchronously evaluating.
 type Future struct {
-	r, c int
-	ch   <-chan struct{}
-	m    MatrixLiteral
+	*futureState
+
+	// wait is the context of the evaluation that uses the result, if any.
+	// Inside of an evaluation, a failure of the Future, or the evaluation's
+	// context being done while waiting for it, aborts the evaluation.
+	wait context.Context
+}
+
+// futureState is the state of a Future, which is shared with the views of it
+// that evaluations use.
+type futureState struct {
+	r, c  int
+	sized <-chan struct{} // if not nil, closed once r and c are known
+	ctx   context.Context
+	ch    <-chan struct{}
+	m     MatrixLiteral
+	err   error
+}
+
+// newFuture creates a Future which is evaluated under ctx.
+func newFuture(ctx context.Context) *Future {
+	return &Future{futureState: &futureState{ctx: ctx}}
+}
+
+// within returns a view of the future for an evaluation under ctx.
+func (m1 *Future) within(ctx context.Context) *Future {
+	return &Future{futureState: m1.futureState, wait: ctx}
+}
+
+// detach returns l, or if it is a view of a future for an evaluation, the
+// future itself, so that it can be returned from the evaluation.
+func detach(l MatrixLiteral) MatrixLiteral {
+	if f, ok := l.(*Future); ok && f.wait != nil {
+		return &Future{futureState: f.futureState}
+	}
+	return l
+}
+
+// Ready determines if the future has finished evaluating, either successfully
+// or not.  It never blocks.
+func (m1 *Future) Ready() bool {
+	select {
+	case <-m1.ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done returns a channel which is closed when the future has finished
+// evaluating, for use in select statements.
+func (m1 *Future) Done() <-chan struct{} {
+	return m1.ch
+}
+
+// Wait waits for the future to finish evaluating, and returns the error which
+// stopped the evaluation, if any.  If ctx is done first, Wait returns the
+// context's error instead, and the evaluation continues.
+func (m1 *Future) Wait(ctx context.Context) error {
+	select {
+	case <-m1.ch:
+		return m1.err
+	default:
+	}
+	select {
+	case <-m1.ch:
+		return m1.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Then creates a Future which waits for this one to finish evaluating, and then
+// evaluates the expression that f returns from its result, under the same
+// context.  If this future fails, the new one fails with the same error,
+// without calling f.  The new future's dimensions aren't known until f has
+// returned, so its Dims method waits for f.
+func (m1 *Future) Then(f func(MatrixLiteral) MatrixExp) *Future {
+	return m1.thenSized(newEvaluation(m1.ctx), f)
+}
+
+// thenSized is Then, but with the context that the new future is evaluated
+// under.
+func (m1 *Future) thenSized(ctx context.Context, f func(MatrixLiteral) MatrixExp) *Future {
+	sized := make(chan struct{})
+	F := newFuture(ctx)
+	F.sized = sized
+	m1.then(F, func(m MatrixLiteral) MatrixExp {
+		M := f(m)
+		F.r, F.c = M.Dims()
+		close(sized)
+		return M
+	})
+	return F
+}
+
+// then evaluates the expression that f returns from the result of m1 into F,
+// once m1 has finished evaluating.
+func (m1 *Future) then(F *Future, f func(MatrixLiteral) MatrixExp) {
+	ch := make(chan struct{})
+	F.ch = ch
+	start := func() {
+		if m1.err != nil {
+			F.err = m1.err
+			close(ch)
+			return
+		}
+		evalFuture(F, ch, func() MatrixExp { return f(m1.m) })
+	}
+	select {
+	case <-m1.ch:
+		start()
+	default:
+		go func() {
+			<-m1.ch
+			start()
+		}()
+	}
+}
+
+// result waits for the future to finish evaluating, and then returns the
+// result, or panics if the evaluation failed.  Inside of an evaluation, it
+// aborts the evaluation instead, including when the evaluation's context is
+// done first.
+func (m1 *Future) result() MatrixLiteral {
+	if m1.wait == nil {
+		<-m1.ch
+		if m1.err != nil {
+			panic(m1.err)
+		}
+		return m1.m
+	}
+	select {
+	case <-m1.ch:
+	case <-m1.wait.Done():
+		panic(abort{m1.wait.Err()})
+	}
+	if m1.err != nil {
+		panic(abort{m1.err})
+	}
+	return m1.m
 }
 
 // String implements the Stringer interface.
 func (m1 *Future) String() string {
-	return "Future{" + strconv.Itoa(m1.r) + ", " + strconv.Itoa(m1.c) + "}"
+	r, c := m1.Dims()
+	return "Future{" + strconv.Itoa(r) + ", " + strconv.Itoa(c) + "}"
 }
 
 // Dims returns the matrix dimensions.
 func (m1 *Future) Dims() (r, c int) {
+	if m1.sized != nil {
+		select {
+		case <-m1.sized:
+		case <-m1.ch:
+		}
+	}
 	r = m1.r
 	c = m1.c
 	return
@@ -48,14 +220,12 @@ func (m1 *Future) Dims() (r, c int) {
 
 // At returns the value at a given row, column index.
 func (m1 *Future) At(r, c int) float64 {
-	<-m1.ch
-	return m1.m.At(r, c)
+	return m1.result().At(r, c)
 }
 
 // Set changes the value at a given row, column index.
 func (m1 *Future) Set(r, c int, v float64) {
-	<-m1.ch
-	m1.m.Set(r, c, v)
+	m1.result().Set(r, c, v)
 }
 
 // Eval returns a matrix literal.
@@ -63,18 +233,36 @@ func (m1 *Future) Eval() MatrixLiteral {
 	return m1
 }
 
-// Copy creates a (deep) copy of the Matrix Expression.
-func (m1 *Future) Copy() MatrixExp {
-	//TODO(jonlawlor): handle the case where we want to copy a running job,
-	// maybe with pub/sub?
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Future) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto waits for the future to finish evaluating, and then copies the
+// result into an existing matrix literal, which must have the same dimensions.
+func (m1 *Future) EvalInto(dst MatrixLiteral) error {
 	<-m1.ch
+	if m1.err != nil {
+		return m1.err
+	}
+	return m1.m.EvalInto(dst)
+}
 
-	return &Future{
-		r:  m1.r,
-		c:  m1.c,
-		ch: m1.ch,
-		m:  m1.m.Copy().Eval(),
+// Copy creates a (deep) copy of the Matrix Expression.  If the future is still
+// evaluating, the copy is a new Future which copies the result once it is
+// ready, and which is unaffected by the original's context after that.
+func (m1 *Future) Copy() MatrixExp {
+	copyResult := func(m MatrixLiteral) MatrixExp {
+		return m.Copy()
+	}
+	if m1.sized != nil {
+		return m1.thenSized(context.Background(), copyResult)
 	}
+	F := newFuture(context.Background())
+	F.r, F.c = m1.r, m1.c
+	m1.then(F, copyResult)
+	return F
 }
 
 // Err returns the first error encountered while constructing the matrix expression.
@@ -82,10 +270,22 @@ func (m1 *Future) Err() error {
 
 	// This is not ideal.  We can't tell if the matrix is invalid until we have
 	// already calculated it, and by that time it is likely that it will have
-	// panicked.  On the other hand, you should only get a future by Async, so
-	// compiling the matrix expression that generates this future should yield
-	// an error before here.
-	<-m1.ch
+	// panicked, which is reported as an ErrPanic.  On the other hand, you
+	// should only get a future by Async, so compiling the matrix expression
+	// that generates this future should yield an error before here.
+	select {
+	case <-m1.ch:
+	default:
+		// Don't wait for an evaluation that has been cancelled to notice.
+		select {
+		case <-m1.ch:
+		case <-m1.ctx.Done():
+			return m1.ctx.Err()
+		}
+	}
+	if m1.err != nil {
+		return m1.err
+	}
 	return m1.m.Err()
 }
 
@@ -142,14 +342,17 @@ func (m1 *Future) DivElem(m2 MatrixExp) MatrixExp {
 	}
 }
 
+// Inv inverts a matrix.
+func (m1 *Future) Inv() MatrixExp {
+	return &Inv{m1}
+}
+
 // AsVector returns a copy of the values in the matrix as a []float64, in row order.
 func (m1 *Future) AsVector() []float64 {
-	<-m1.ch
-	return m1.m.AsVector()
+	return m1.result().AsVector()
 }
 
 // AsGeneral returns the matrix as a blas64.General (not a copy!)
 func (m1 *Future) AsGeneral() blas64.General {
-	<-m1.ch
-	return m1.m.AsGeneral()
+	return m1.result().AsGeneral()
 }
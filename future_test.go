@@ -0,0 +1,126 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"testing"
+)
+
+// heldScheduler runs each function once it is released.
+type heldScheduler chan struct{}
+
+func (s heldScheduler) Go(f func()) {
+	go func() {
+		<-s
+		f()
+	}()
+}
+
+func TestFuturePanic(t *testing.T) {
+	t.Parallel()
+	// The inner dimensions don't match, so blas panics.
+	a := &General{rnd(2, 3)}
+	m := &Mul{Left: a, Right: a}
+	f := NewFuture(m)
+
+	err := f.Err()
+	if _, ok := err.(*ErrPanic); !ok {
+		t.Fatalf("%v.Err() equals %v, want an ErrPanic", f, err)
+	}
+	if got := f.EvalInto(&General{zeros(2, 3)}); got != err {
+		t.Errorf("%v.EvalInto() equals %v, want %v", f, got, err)
+	}
+	if got := f.Copy().Err(); got == nil {
+		t.Errorf("%v.Copy().Err() equals %v, want %v", f, got, err)
+	}
+	for _, fn := range []func(){
+		func() { f.At(0, 0) },
+		func() { f.Set(0, 0, 1) },
+		func() { f.AsVector() },
+		func() { f.AsGeneral() },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != err {
+					t.Errorf("%v panicked with %v, want %v", f, r, err)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestFutureWait(t *testing.T) {
+	t.Parallel()
+	a := &General{rnd(3, 3)}
+	held := make(heldScheduler)
+	f := NewFutureContext(WithScheduler(context.Background(), held), a.Scale(2))
+	g := f.Then(func(m MatrixLiteral) MatrixExp {
+		return m.Mul(&General{rnd(3, 2)})
+	})
+	c := f.Copy()
+
+	if f.Ready() {
+		t.Errorf("%v.Ready() equals true before it was evaluated", f)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.Wait(ctx); err != context.Canceled {
+		t.Errorf("%v.Wait() equals %v, want %v", f, err, context.Canceled)
+	}
+
+	close(held)
+	if err := f.Wait(context.Background()); err != nil {
+		t.Errorf("%v.Wait() equals %v, want nil", f, err)
+	}
+	select {
+	case <-f.Done():
+	default:
+		t.Errorf("%v.Done() is not closed after Wait", f)
+	}
+	if !f.Ready() {
+		t.Errorf("%v.Ready() equals false after Wait", f)
+	}
+	if r, c := g.Dims(); r != 3 || c != 2 {
+		t.Errorf("%v.Dims() equals %v, %v, want 3, 2", g, r, c)
+	}
+	want := a.Scale(2).Mul(&General{rnd(3, 2)}).Eval()
+	if err := g.Wait(context.Background()); err != nil || !Equals(g, want) {
+		t.Errorf("%v.Then() equals %v, %v, want %v", f, g, err, want)
+	}
+
+	// The copy doesn't share the original's result.
+	if err := c.Err(); err != nil || !Equals(c, f) {
+		t.Errorf("%v.Copy() equals %v, %v, want %v", f, c, err, f)
+	}
+	c.(*Future).Set(0, 0, 100)
+	if f.At(0, 0) == 100 {
+		t.Errorf("%v.Copy() shares its result with the original", f)
+	}
+
+	// The copy of a future from Then isn't affected by the original's
+	// context.
+	ctx, cancel = context.WithCancel(context.Background())
+	h := NewFutureContext(ctx, a).Then(func(m MatrixLiteral) MatrixExp {
+		return m.Scale(2)
+	})
+	h.Wait(context.Background())
+	cancel()
+	if hc := h.Copy(); hc.Err() != nil || !Equals(hc, h) {
+		t.Errorf("%v.Copy() equals %v, %v, want %v", h, hc, hc.Err(), h)
+	}
+
+	// Failures are passed along without calling f.
+	p := NewFuture(&Mul{Left: &General{rnd(2, 3)}, Right: &General{rnd(2, 3)}})
+	called := false
+	q := p.Then(func(m MatrixLiteral) MatrixExp {
+		called = true
+		return m
+	})
+	if err := q.Wait(context.Background()); err != p.Err() || called {
+		t.Errorf("%v.Then() failed with %v, want %v", p, err, p.Err())
+	}
+}
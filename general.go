@@ -5,6 +5,7 @@
 package matrixexp
 
 import (
+	"context"
 	"fmt"
 	"github.com/gonum/blas/blas64"
 )
@@ -40,6 +41,22 @@ func (m1 *General) Eval() MatrixLiteral {
 	return m1
 }
 
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *General) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto copies the matrix into an existing matrix literal, which must have
+// the same dimensions.
+func (m1 *General) EvalInto(dst MatrixLiteral) error {
+	if err := checkDst(m1, dst); err != nil {
+		return err
+	}
+	copyInto(dst.AsGeneral(), m1.General)
+	return nil
+}
+
 // Copy creates a (deep) copy of the Matrix Expression.
 func (m1 *General) Copy() MatrixExp {
 	v := make([]float64, len(m1.Data))
@@ -127,14 +144,17 @@ func (m1 *General) DivElem(m2 MatrixExp) MatrixExp {
 	}
 }
 
+// Inv inverts a matrix.
+func (m1 *General) Inv() MatrixExp {
+	return &Inv{m1}
+}
+
 // AsVector returns a copy of the values in the matrix as a []float64, in row order.
 func (m1 *General) AsVector() []float64 {
-	// TODO(jonlawlor): make use of a pool.
-	v := make([]float64, m1.Rows*m1.Cols)
+	v := getAllocator().Get(m1.Rows * m1.Cols)
 	for i := 0; i < m1.Rows; i++ {
 		copy(v[i*m1.Cols:(i+1)*m1.Cols], m1.Data[i*m1.Stride:i*m1.Stride+m1.Cols])
 	}
-	copy(v, m1.Data)
 	return v
 }
 
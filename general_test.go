@@ -430,6 +430,23 @@ func TestEval(t *testing.T) {
 	}
 }
 
+func TestEvalInto(t *testing.T) {
+	t.Parallel()
+	for ti, tt := range TestFixtures {
+		m := tt.expr
+		want := &General{tt.want}
+		got := GeneralOnes(tt.r, tt.c).(*General)
+		if err := m.EvalInto(got); err != nil {
+			t.Errorf("%d: %s EvalInto returned error %v", ti, tt.name, err)
+		} else if !Equals(got, want) {
+			t.Errorf("%d: %s EvalInto equals %v, want %v", ti, tt.name, got, want)
+		}
+		if err := m.EvalInto(GeneralZeros(tt.r+1, tt.c).(*General)); err == nil {
+			t.Errorf("%d: %s EvalInto a mismatched destination returned nil error", ti, tt.name)
+		}
+	}
+}
+
 func TestAt(t *testing.T) {
 	t.Parallel()
 	for ti, tt := range TestFixtures {
@@ -0,0 +1,198 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas/blas64"
+	"math"
+	"strconv"
+)
+
+// Ger represents the rank one update A + Alpha * U * Vᵀ, where U and V are
+// column vectors.  It is evaluated with a single call to blas64.Ger, without
+// forming the outer product.
+type Ger struct {
+	A     MatrixExp
+	Alpha float64
+	U     MatrixExp
+	V     MatrixExp
+}
+
+// String implements the Stringer interface.
+func (m1 *Ger) String() string {
+	return "Ger{" + m1.A.String() + ", " + strconv.FormatFloat(m1.Alpha, 'g', -1, 64) + ", " + m1.U.String() + ", " + m1.V.String() + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Ger) Dims() (r, c int) {
+	r, c = m1.A.Dims()
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Ger) At(r, c int) float64 {
+	return m1.A.At(r, c) + m1.Alpha*m1.U.At(r, 0)*m1.V.At(c, 0)
+}
+
+// Eval returns a matrix literal.
+func (m1 *Ger) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Ger) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Ger) evalCtx(ctx context.Context) MatrixLiteral {
+	am := evalCtx(ctx, m1.A)
+	um := evalCtx(ctx, m1.U)
+	vm := evalCtx(ctx, m1.V)
+	m := writable(m1.A, am)
+	blas64.Ger(m1.Alpha, colVector(um.AsGeneral()), colVector(vm.AsGeneral()), m)
+	release(m1.U, um)
+	release(m1.V, vm)
+	return &General{m}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Ger) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *Ger) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	if err := checkDst(m1, dst); err != nil {
+		return err
+	}
+	d := dst.AsGeneral()
+	um := evalCtx(ctx, m1.U)
+	vm := evalCtx(ctx, m1.V)
+	am := evalCtx(ctx, m1.A)
+	u := disjoint(d, um.AsGeneral())
+	v := disjoint(d, vm.AsGeneral())
+	copyInto(d, am.AsGeneral())
+	blas64.Ger(m1.Alpha, colVector(u), colVector(v), d)
+	release(m1.A, am)
+	release(m1.U, um)
+	release(m1.V, vm)
+	return nil
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Ger) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Ger) Copy() MatrixExp {
+	return &Ger{
+		A:     m1.A.Copy(),
+		Alpha: m1.Alpha,
+		U:     m1.U.Copy(),
+		V:     m1.V.Copy(),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Ger) Err() error {
+	if err := m1.A.Err(); err != nil {
+		return err
+	}
+	o := &Outer{
+		U: m1.U,
+		V: m1.V,
+	}
+	if err := o.Err(); err != nil {
+		return err
+	}
+
+	r1, c1 := m1.A.Dims()
+	r2, c2 := o.Dims()
+	if r1 != r2 || c1 != c2 {
+		return ErrDimMismatch{
+			R1: r1,
+			C1: c1,
+			R2: r2,
+			C2: c2,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *Ger) T() MatrixExp {
+	return &Ger{
+		A:     m1.A.T(),
+		Alpha: m1.Alpha,
+		U:     m1.V,
+		V:     m1.U,
+	}
+}
+
+// Add two matrices together.
+func (m1 *Ger) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Ger) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Ger) Scale(c float64) MatrixExp {
+	if math.IsNaN(c) || math.IsNaN(m1.Alpha) {
+		// Keep scalar wildcards of the rewrite package apart, like Scale does.
+		return &Scale{
+			C: c,
+			M: m1,
+		}
+	}
+	return &Ger{
+		A:     m1.A.Scale(c),
+		Alpha: m1.Alpha * c,
+		U:     m1.U,
+		V:     m1.V,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Ger) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Ger) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Ger) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Ger) Inv() MatrixExp {
+	return &Inv{m1}
+}
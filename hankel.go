@@ -0,0 +1,215 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"fmt"
+	"github.com/gonum/blas/blas64"
+)
+
+// Hankel represents a matrix which is constant along each anti-diagonal.  It
+// is defined by its first column and last row, which must agree on the value
+// of the bottom left element.
+type Hankel struct {
+	Col []float64
+	Row []float64
+}
+
+// String implements the Stringer interface.
+func (m1 *Hankel) String() string {
+	return fmt.Sprintf("Hankel{%v, %v}", m1.Col, m1.Row)
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Hankel) Dims() (r, c int) {
+	r, c = len(m1.Col), len(m1.Row)
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Hankel) At(r, c int) float64 {
+	if k := r + c; k < len(m1.Col) {
+		return m1.Col[k]
+	}
+	return m1.Row[r+c-len(m1.Col)+1]
+}
+
+// Eval returns a matrix literal.
+func (m1 *Hankel) Eval() MatrixLiteral {
+	r, c := m1.Dims()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   c,
+		Stride: c,
+		Data:   getAllocator().Get(r * c),
+	}
+	for i := 0; i < r; i++ {
+		row := m.Data[i*c : (i+1)*c]
+		for j := range row {
+			row[j] = m1.At(i, j)
+		}
+	}
+	return &General{m}
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Hankel) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Hankel) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// antidiagonals returns the values of the anti-diagonals of the matrix, from
+// the top left to the bottom right.
+func (m1 *Hankel) antidiagonals() []float64 {
+	r, c := m1.Dims()
+	h := make([]float64, r+c-1)
+	copy(h, m1.Col)
+	copy(h[r:], m1.Row[1:])
+	return h
+}
+
+// leftMul multiplies the Hankel matrix by a matrix literal.  Each column of
+// the product is a linear convolution of the anti-diagonals of the matrix
+// with the reversed column, which is found with the FFT.
+func (m1 *Hankel) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	r, c := m1.Dims()
+	x := m2.AsGeneral()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(r * x.Cols),
+	}
+	if r == 0 || c == 0 {
+		return &General{m}
+	}
+
+	cv := newConvolver(m1.antidiagonals(), c)
+	for j := 0; j < x.Cols; j++ {
+		check(ctx)
+		xr := column(x, j)
+		for i, k := 0, len(xr)-1; i < k; i, k = i+1, k-1 {
+			xr[i], xr[k] = xr[k], xr[i]
+		}
+		y := cv.convolve(xr)
+		for i := 0; i < r; i++ {
+			m.Data[i*m.Stride+j] = y[i+c-1]
+		}
+	}
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Hankel) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Hankel) Copy() MatrixExp {
+	return &Hankel{
+		Col: scaleCopy(1, m1.Col),
+		Row: scaleCopy(1, m1.Row),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Hankel) Err() error {
+	if len(m1.Col) == 0 && len(m1.Row) == 0 {
+		return nil
+	}
+	if len(m1.Col) == 0 || len(m1.Row) == 0 {
+		return ErrSharedElement{Col: len(m1.Col), Row: len(m1.Row)}
+	}
+	return sharedElement(m1.Col, m1.Row, m1.Col[len(m1.Col)-1])
+}
+
+// T transposes a matrix.  The transpose of a Hankel matrix is Hankel, with the
+// same anti-diagonals.
+func (m1 *Hankel) T() MatrixExp {
+	r, c := m1.Dims()
+	if r == 0 || c == 0 {
+		return &Hankel{
+			Col: scaleCopy(1, m1.Row),
+			Row: scaleCopy(1, m1.Col),
+		}
+	}
+	h := m1.antidiagonals()
+	return &Hankel{
+		Col: h[:c],
+		Row: h[c-1:],
+	}
+}
+
+// Add two matrices together.  The sum of two Hankel matrices is Hankel.
+func (m1 *Hankel) Add(m2 MatrixExp) MatrixExp {
+	if h, ok := m2.(*Hankel); ok && sameDims(m1, h) {
+		return &Hankel{
+			Col: addCopy(m1.Col, 1, h.Col),
+			Row: addCopy(m1.Row, 1, h.Row),
+		}
+	}
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.  The difference of two
+// Hankel matrices is Hankel.
+func (m1 *Hankel) Sub(m2 MatrixExp) MatrixExp {
+	if h, ok := m2.(*Hankel); ok && sameDims(m1, h) {
+		return &Hankel{
+			Col: addCopy(m1.Col, -1, h.Col),
+			Row: addCopy(m1.Row, -1, h.Row),
+		}
+	}
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Hankel) Scale(c float64) MatrixExp {
+	return &Hankel{
+		Col: scaleCopy(c, m1.Col),
+		Row: scaleCopy(c, m1.Row),
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Hankel) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Hankel) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Hankel) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Hankel) Inv() MatrixExp {
+	return &Inv{m1}
+}
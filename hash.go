@@ -0,0 +1,223 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// Structural comparison of matrix expressions.  Two expressions are
+// structurally equal if they are the same kind of node, with equal exported
+// fields (such as Scale.C or Toeplitz.Col), and structurally equal
+// subexpressions.  Literals are only structurally equal to themselves, because
+// they can be modified after the comparison is made.  Memos are compared by
+// the expressions they hold.  Expressions that are not structs, such as the
+// wildcards in the rewrite package, are also only equal to themselves.
+//
+// Unlike Equals, neither function evaluates the expressions, so they are
+// suitable for caches, common subexpression elimination, and rule matching.
+
+// StructEqual determines if two matrix expressions are structurally equal.
+func StructEqual(m1, m2 MatrixExp) bool {
+	m1, m2 = unmemo(m1), unmemo(m2)
+	r1 := reflect.ValueOf(m1)
+	r2 := reflect.ValueOf(m2)
+	if r1.Type() != r2.Type() {
+		return false
+	}
+	if !structNode(m1) {
+		return nodeID(r1) == nodeID(r2)
+	}
+	return equalValue(r1.Elem(), r2.Elem())
+}
+
+// Hash returns a hash of the structure of a matrix expression.  Structurally
+// equal expressions have the same hash.
+func Hash(m MatrixExp) uint64 {
+	h := fnv.New64a()
+	hashExp(h, m)
+	return h.Sum64()
+}
+
+// unmemo returns the expression held by a memo.
+func unmemo(m MatrixExp) MatrixExp {
+	for {
+		memo, ok := m.(*Memo)
+		if !ok {
+			return m
+		}
+		m = memo.M
+	}
+}
+
+// structNode determines if m is a pointer to a struct which is not a literal.
+func structNode(m MatrixExp) bool {
+	if _, ok := m.(MatrixLiteral); ok {
+		return false
+	}
+	r := reflect.ValueOf(m)
+	return r.Kind() == reflect.Ptr && r.Elem().Kind() == reflect.Struct
+}
+
+// nodeID returns a value which identifies a node that is only equal to
+// itself.
+func nodeID(r reflect.Value) interface{} {
+	if r.Kind() == reflect.Ptr {
+		return r.Pointer()
+	}
+	if !r.Type().Comparable() {
+		return new(int) // equal to nothing
+	}
+	return r.Interface()
+}
+
+// equalValue compares the exported contents of two values of the same type.
+func equalValue(v1, v2 reflect.Value) bool {
+	switch v1.Kind() {
+	case reflect.Interface:
+		if v1.IsNil() || v2.IsNil() {
+			return v1.IsNil() && v2.IsNil()
+		}
+		if m1, ok := v1.Interface().(MatrixExp); ok {
+			m2, ok := v2.Interface().(MatrixExp)
+			return ok && StructEqual(m1, m2)
+		}
+		if v1.Elem().Type() != v2.Elem().Type() {
+			return false
+		}
+		return equalValue(v1.Elem(), v2.Elem())
+	case reflect.Ptr:
+		if v1.IsNil() || v2.IsNil() {
+			return v1.IsNil() && v2.IsNil()
+		}
+		if m1, ok := v1.Interface().(MatrixExp); ok {
+			return StructEqual(m1, v2.Interface().(MatrixExp))
+		}
+		return v1.Pointer() == v2.Pointer() || equalValue(v1.Elem(), v2.Elem())
+	case reflect.Struct:
+		for i := 0; i < v1.NumField(); i++ {
+			if v1.Type().Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			if !equalValue(v1.Field(i), v2.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if v1.Len() != v2.Len() {
+			return false
+		}
+		for i := 0; i < v1.Len(); i++ {
+			if !equalValue(v1.Index(i), v2.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Float32, reflect.Float64:
+		// compare bits, so that NaN is equal to itself
+		return math.Float64bits(v1.Float()) == math.Float64bits(v2.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c1, c2 := v1.Complex(), v2.Complex()
+		return math.Float64bits(real(c1)) == math.Float64bits(real(c2)) &&
+			math.Float64bits(imag(c1)) == math.Float64bits(imag(c2))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v1.Int() == v2.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v1.Uint() == v2.Uint()
+	case reflect.Bool:
+		return v1.Bool() == v2.Bool()
+	case reflect.String:
+		return v1.String() == v2.String()
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.UnsafePointer:
+		return v1.Pointer() == v2.Pointer()
+	}
+	return false
+}
+
+// hashExp writes the structure of m to h.
+func hashExp(h hash.Hash64, m MatrixExp) {
+	m = unmemo(m)
+	r := reflect.ValueOf(m)
+	h.Write([]byte(r.Type().String()))
+	if !structNode(m) {
+		// only equal to itself, so any value consistent with identity will
+		// do, and the dimensions are cheap.
+		rows, cols := m.Dims()
+		hashUint(h, uint64(rows))
+		hashUint(h, uint64(cols))
+		if r.Kind() == reflect.Ptr {
+			hashUint(h, uint64(r.Pointer()))
+		}
+		return
+	}
+	hashValue(h, r.Elem())
+}
+
+// hashValue writes the exported contents of a value to h.
+func hashValue(h hash.Hash64, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			hashUint(h, 0)
+			return
+		}
+		if m, ok := v.Interface().(MatrixExp); ok {
+			hashExp(h, m)
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			// pointers may be equal by identity, so only the contents
+			// can be hashed.
+			hashValue(h, v.Elem())
+			return
+		}
+		h.Write([]byte(v.Elem().Type().String()))
+		hashValue(h, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			hashValue(h, v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		hashUint(h, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Float32, reflect.Float64:
+		hashUint(h, math.Float64bits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		hashUint(h, math.Float64bits(real(c)))
+		hashUint(h, math.Float64bits(imag(c)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		hashUint(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		hashUint(h, v.Uint())
+	case reflect.Bool:
+		if v.Bool() {
+			hashUint(h, 1)
+		} else {
+			hashUint(h, 0)
+		}
+	case reflect.String:
+		h.Write([]byte(v.String()))
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.UnsafePointer:
+		hashUint(h, uint64(v.Pointer()))
+	}
+}
+
+// hashUint writes an integer to h.
+func hashUint(h hash.Hash64, x uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	h.Write(b[:])
+}
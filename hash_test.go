@@ -0,0 +1,45 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStructEqual(t *testing.T) {
+	t.Parallel()
+	a := &General{rnd(3, 3)}
+	b := &General{rnd(3, 3)}
+	c := &General{rnd(3, 3)} // the same values as b
+	for _, tt := range []struct {
+		m1, m2 MatrixExp
+		want   bool
+	}{
+		{m1: a, m2: a, want: true},
+		{m1: b, m2: c, want: false},
+		{m1: a.Mul(b), m2: a.Mul(b), want: true},
+		{m1: a.Mul(b), m2: b.Mul(a), want: false},
+		{m1: a.Mul(b), m2: a.MulElem(b), want: false},
+		{m1: a.Scale(2).T(), m2: a.Scale(2).T(), want: true},
+		{m1: a.Scale(2), m2: a.Scale(3), want: false},
+		{m1: a.Scale(math.NaN()), m2: a.Scale(math.NaN()), want: true},
+		{m1: &Pow{M: a, K: 2}, m2: &Pow{M: a, K: 2}, want: true},
+		{m1: &Pow{M: a, K: 2}, m2: &Pow{M: a, K: 3}, want: false},
+		{m1: &Toeplitz{Col: []float64{1, 2}, Row: []float64{1, 3}}, m2: &Toeplitz{Col: []float64{1, 2}, Row: []float64{1, 3}}, want: true},
+		{m1: &Toeplitz{Col: []float64{1, 2}, Row: []float64{1, 3}}, m2: &Toeplitz{Col: []float64{1, 2}, Row: []float64{1, 4}}, want: false},
+		{m1: NewMemo(a.Add(b), 2), m2: a.Add(b), want: true},
+	} {
+		if got := StructEqual(tt.m1, tt.m2); got != tt.want {
+			t.Errorf("StructEqual(%v, %v) equals %v, want %v", tt.m1, tt.m2, got, tt.want)
+		}
+		if got := StructEqual(tt.m2, tt.m1); got != tt.want {
+			t.Errorf("StructEqual(%v, %v) equals %v, want %v", tt.m2, tt.m1, got, tt.want)
+		}
+		if h1, h2 := Hash(tt.m1), Hash(tt.m2); tt.want && h1 != h2 {
+			t.Errorf("Hash(%v) equals %v, want Hash(%v) = %v", tt.m1, h1, tt.m2, h2)
+		}
+	}
+}
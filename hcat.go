@@ -0,0 +1,197 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas/blas64"
+)
+
+// HCat represents the horizontal concatenation of two matrices, [Left Right].
+type HCat struct {
+	Left  MatrixExp
+	Right MatrixExp
+}
+
+// String implements the Stringer interface.
+func (m1 *HCat) String() string {
+	return "HCat{" + m1.Left.String() + ", " + m1.Right.String() + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *HCat) Dims() (r, c int) {
+	r, c1 := m1.Left.Dims()
+	_, c2 := m1.Right.Dims()
+	c = c1 + c2
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *HCat) At(r, c int) float64 {
+	if _, c1 := m1.Left.Dims(); c >= c1 {
+		return m1.Right.At(r, c-c1)
+	}
+	return m1.Left.At(r, c)
+}
+
+// Eval returns a matrix literal.
+func (m1 *HCat) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *HCat) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *HCat) evalCtx(ctx context.Context) MatrixLiteral {
+	r, c := m1.Dims()
+	lm := evalCtx(ctx, m1.Left)
+	rm := evalCtx(ctx, m1.Right)
+	left := lm.AsGeneral()
+	right := rm.AsGeneral()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   c,
+		Stride: c,
+		Data:   getAllocator().Get(r * c),
+	}
+	for i := 0; i < r; i++ {
+		copy(m.Data[i*c:i*c+left.Cols], left.Data[i*left.Stride:i*left.Stride+left.Cols])
+		copy(m.Data[i*c+left.Cols:(i+1)*c], right.Data[i*right.Stride:i*right.Stride+right.Cols])
+	}
+	release(m1.Left, lm)
+	release(m1.Right, rm)
+	return &General{m}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *HCat) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *HCat) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	if err := checkDst(m1, dst); err != nil {
+		return err
+	}
+	d := dst.AsGeneral()
+	lm := evalCtx(ctx, m1.Left)
+	rm := evalCtx(ctx, m1.Right)
+	left := disjoint(d, lm.AsGeneral())
+	right := disjoint(d, rm.AsGeneral())
+	copyInto(blas64.General{
+		Rows:   d.Rows,
+		Cols:   left.Cols,
+		Stride: d.Stride,
+		Data:   d.Data,
+	}, left)
+	if right.Cols > 0 {
+		copyInto(blas64.General{
+			Rows:   d.Rows,
+			Cols:   right.Cols,
+			Stride: d.Stride,
+			Data:   d.Data[left.Cols:],
+		}, right)
+	}
+	release(m1.Left, lm)
+	release(m1.Right, rm)
+	return nil
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *HCat) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *HCat) Copy() MatrixExp {
+	return &HCat{
+		Left:  m1.Left.Copy(),
+		Right: m1.Right.Copy(),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *HCat) Err() error {
+	if err := m1.Left.Err(); err != nil {
+		return err
+	}
+	if err := m1.Right.Err(); err != nil {
+		return err
+	}
+
+	r1, c1 := m1.Left.Dims()
+	r2, c2 := m1.Right.Dims()
+	if r1 != r2 {
+		return ErrDimMismatch{
+			R1: r1,
+			C1: c1,
+			R2: r2,
+			C2: c2,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *HCat) T() MatrixExp {
+	return &T{m1}
+}
+
+// Add two matrices together.
+func (m1 *HCat) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *HCat) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *HCat) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *HCat) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *HCat) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *HCat) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *HCat) Inv() MatrixExp {
+	return &Inv{m1}
+}
@@ -4,7 +4,189 @@
 
 package matrixexp
 
-// Not yet implemented.  I'm hoping to use getrf -> getri from gonum/lapack, but
-// getri is not yet implemented.  There will be some other speedbumps along the
-// way (specifically, the At function and also making sane test cases) and Inv
-// isn't all that useful, so I'm happy to leave it for now.
+import (
+	"context"
+	"github.com/gonum/blas/blas64"
+	"math"
+)
+
+// Inv represents matrix inversion.
+//
+// I had hoped to use getrf -> getri from gonum/lapack, but getri is not yet
+// implemented, so for now the inverse is found with Gauss-Jordan elimination.
+type Inv struct {
+	M MatrixExp
+}
+
+// String implements the Stringer interface.
+func (m1 *Inv) String() string {
+	return m1.M.String() + ".Inv()"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Inv) Dims() (r, c int) {
+	r, c = m1.M.Dims()
+	return
+}
+
+// At returns the value at a given row, column index.  Every element of the
+// inverse depends on every element of the input, so this evaluates the whole
+// inverse.
+func (m1 *Inv) At(r, c int) float64 {
+	return m1.Eval().At(r, c)
+}
+
+// Eval returns a matrix literal.  The inverse of a singular matrix will
+// contain non-finite values.
+func (m1 *Inv) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Inv) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Inv) evalCtx(ctx context.Context) MatrixLiteral {
+	n, _ := m1.M.Dims()
+	a := writable(m1.M, evalCtx(ctx, m1.M))
+	inv := invert(ctx, a.Data, n)
+	getAllocator().Put(a.Data)
+	return &General{blas64.General{
+		Rows:   n,
+		Cols:   n,
+		Stride: n,
+		Data:   inv,
+	}}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Inv) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Inv) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Inv) Copy() MatrixExp {
+	return &Inv{
+		M: m1.M.Copy(),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Inv) Err() error {
+	if err := m1.M.Err(); err != nil {
+		return err
+	}
+	if r, c := m1.M.Dims(); r != c {
+		return ErrNotSquare{
+			R: r,
+			C: c,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *Inv) T() MatrixExp {
+	return &T{m1}
+}
+
+// Add two matrices together.
+func (m1 *Inv) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Inv) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Inv) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Inv) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Inv) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Inv) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Inv) Inv() MatrixExp {
+	return m1.M
+}
+
+// invert returns the inverse of the n x n matrix stored in row order in a,
+// using Gauss-Jordan elimination with partial pivoting under ctx.  The contents
+// of a are destroyed.
+func invert(ctx context.Context, a []float64, n int) []float64 {
+	inv := getAllocator().Get(n * n)
+	for i := 0; i < n; i++ {
+		inv[i*n+i] = 1
+	}
+	for k := 0; k < n; k++ {
+		check(ctx)
+
+		// Find the row with the largest pivot.
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(a[i*n+k]) > math.Abs(a[p*n+k]) {
+				p = i
+			}
+		}
+		if p != k {
+			blas64.Swap(n, blas64.Vector{Inc: 1, Data: a[k*n : (k+1)*n]}, blas64.Vector{Inc: 1, Data: a[p*n : (p+1)*n]})
+			blas64.Swap(n, blas64.Vector{Inc: 1, Data: inv[k*n : (k+1)*n]}, blas64.Vector{Inc: 1, Data: inv[p*n : (p+1)*n]})
+		}
+
+		d := 1 / a[k*n+k]
+		ak := blas64.Vector{Inc: 1, Data: a[k*n : (k+1)*n]}
+		ik := blas64.Vector{Inc: 1, Data: inv[k*n : (k+1)*n]}
+		blas64.Scal(n, d, ak)
+		blas64.Scal(n, d, ik)
+
+		// Eliminate the pivot column from every other row.
+		for i := 0; i < n; i++ {
+			if f := a[i*n+k]; i != k && f != 0 {
+				blas64.Axpy(n, -f, ak, blas64.Vector{Inc: 1, Data: a[i*n : (i+1)*n]})
+				blas64.Axpy(n, -f, ik, blas64.Vector{Inc: 1, Data: inv[i*n : (i+1)*n]})
+			}
+		}
+	}
+	return inv
+}
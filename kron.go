@@ -0,0 +1,243 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+)
+
+// Kron represents the Kronecker product of two matrices.  It is never
+// materialized unless it is evaluated directly.
+type Kron struct {
+	Left  MatrixExp
+	Right MatrixExp
+}
+
+// String implements the Stringer interface.
+func (m1 *Kron) String() string {
+	return "Kron{" + m1.Left.String() + ", " + m1.Right.String() + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Kron) Dims() (r, c int) {
+	r1, c1 := m1.Left.Dims()
+	r2, c2 := m1.Right.Dims()
+	r, c = r1*r2, c1*c2
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Kron) At(r, c int) float64 {
+	r2, c2 := m1.Right.Dims()
+	return m1.Left.At(r/r2, c/c2) * m1.Right.At(r%r2, c%c2)
+}
+
+// Eval returns a matrix literal.
+func (m1 *Kron) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Kron) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Kron) evalCtx(ctx context.Context) MatrixLiteral {
+	lm := evalCtx(ctx, m1.Left)
+	rm := evalCtx(ctx, m1.Right)
+	a := lm.AsGeneral()
+	b := rm.AsGeneral()
+	r, c := m1.Dims()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   c,
+		Stride: c,
+		Data:   getAllocator().Get(r * c),
+	}
+	for i := 0; i < a.Rows; i++ {
+		for j := 0; j < a.Cols; j++ {
+			v := a.Data[i*a.Stride+j]
+			for k := 0; k < b.Rows; k++ {
+				row := m.Data[(i*b.Rows+k)*m.Stride+j*b.Cols:]
+				for l, w := range b.Data[k*b.Stride : k*b.Stride+b.Cols] {
+					row[l] = v * w
+				}
+			}
+		}
+	}
+	release(m1.Left, lm)
+	release(m1.Right, rm)
+	return &General{m}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Kron) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// leftMul multiplies the Kronecker product by a matrix literal using the
+// identity (A ⊗ B) vec(X) = vec(B X Aᵀ) on each column, so the product is never
+// formed.
+func (m1 *Kron) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	lm := evalCtx(ctx, m1.Left)
+	rm := evalCtx(ctx, m1.Right)
+	a := lm.AsGeneral()
+	b := rm.AsGeneral()
+	x := m2.AsGeneral()
+
+	// A column of x is vec(X) for a b.Cols x a.Cols matrix X, which in row
+	// order is Xᵀ, and the result vec(B X Aᵀ) in row order is A Xᵀ Bᵀ.
+	m := blas64.General{
+		Rows:   a.Rows * b.Rows,
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(a.Rows * b.Rows * x.Cols),
+	}
+	xt := blas64.General{
+		Rows:   a.Cols,
+		Cols:   b.Cols,
+		Stride: b.Cols,
+		Data:   getAllocator().Get(a.Cols * b.Cols),
+	}
+	axt := blas64.General{
+		Rows:   a.Rows,
+		Cols:   b.Cols,
+		Stride: b.Cols,
+		Data:   getAllocator().Get(a.Rows * b.Cols),
+	}
+	z := blas64.General{
+		Rows:   a.Rows,
+		Cols:   b.Rows,
+		Stride: b.Rows,
+		Data:   getAllocator().Get(a.Rows * b.Rows),
+	}
+	for j := 0; j < x.Cols; j++ {
+		check(ctx)
+		for i := range xt.Data {
+			xt.Data[i] = x.Data[i*x.Stride+j]
+		}
+		blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, a, xt, 0, axt)
+		blas64.Gemm(blas.NoTrans, blas.Trans, 1, axt, b, 0, z)
+		for i, v := range z.Data {
+			m.Data[i*m.Stride+j] = v
+		}
+	}
+	alloc := getAllocator()
+	alloc.Put(xt.Data)
+	alloc.Put(axt.Data)
+	alloc.Put(z.Data)
+	release(m1.Left, lm)
+	release(m1.Right, rm)
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Kron) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Kron) Copy() MatrixExp {
+	return &Kron{
+		Left:  m1.Left.Copy(),
+		Right: m1.Right.Copy(),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Kron) Err() error {
+	if err := m1.Left.Err(); err != nil {
+		return err
+	}
+	return m1.Right.Err()
+}
+
+// T transposes a matrix.  The transpose of A ⊗ B is Aᵀ ⊗ Bᵀ.
+func (m1 *Kron) T() MatrixExp {
+	return &Kron{
+		Left:  m1.Left.T(),
+		Right: m1.Right.T(),
+	}
+}
+
+// Add two matrices together.
+func (m1 *Kron) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Kron) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.  The scale is folded into the left
+// factor.
+func (m1 *Kron) Scale(c float64) MatrixExp {
+	return &Kron{
+		Left:  m1.Left.Scale(c),
+		Right: m1.Right,
+	}
+}
+
+// Mul performs matrix multiplication.  The product of two Kronecker products
+// with compatible factors is (A ⊗ B)(C ⊗ D) = AC ⊗ BD.
+func (m1 *Kron) Mul(m2 MatrixExp) MatrixExp {
+	if k, ok := m2.(*Kron); ok {
+		_, c1 := m1.Left.Dims()
+		r1, _ := k.Left.Dims()
+		_, c2 := m1.Right.Dims()
+		r2, _ := k.Right.Dims()
+		if c1 == r1 && c2 == r2 {
+			return &Kron{
+				Left:  m1.Left.Mul(k.Left),
+				Right: m1.Right.Mul(k.Right),
+			}
+		}
+	}
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Kron) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Kron) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.  If both factors are square, the inverse of A ⊗ B is
+// A⁻¹ ⊗ B⁻¹.
+func (m1 *Kron) Inv() MatrixExp {
+	r1, c1 := m1.Left.Dims()
+	r2, c2 := m1.Right.Dims()
+	if r1 == c1 && r2 == c2 {
+		return &Kron{
+			Left:  m1.Left.Inv(),
+			Right: m1.Right.Inv(),
+		}
+	}
+	return &Inv{m1}
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"github.com/gonum/blas/blas64"
+	"testing"
+)
+
+// blaskron forms a Kronecker product for comparison.
+func blaskron(a, b blas64.General) blas64.General {
+	g := zeros(a.Rows*b.Rows, a.Cols*b.Cols)
+	for i := 0; i < g.Rows; i++ {
+		for j := 0; j < g.Cols; j++ {
+			g.Data[i*g.Stride+j] = a.Data[(i/b.Rows)*a.Stride+j/b.Cols] * b.Data[(i%b.Rows)*b.Stride+j%b.Cols]
+		}
+	}
+	return g
+}
+
+func TestKron(t *testing.T) {
+	t.Parallel()
+	a := &General{rnd(2, 3)}
+	b := &General{blas64.General{Rows: 4, Cols: 2, Stride: 2, Data: []float64{1, 2, 3, 4, 5, 6, 7, 8}}}
+	k := &Kron{Left: a, Right: b}
+	want := &General{blaskron(a.General, b.General)}
+
+	if r, c := k.Dims(); r != 8 || c != 6 {
+		t.Errorf("%v.Dims() equals (%d, %d), want (8, 6)", k, r, c)
+	}
+	if got := k.Eval(); !Equals(got, want) {
+		t.Errorf("%v equals %v, want %v", k, got, want)
+	}
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 6; j++ {
+			if got, w := k.At(i, j), want.At(i, j); got != w {
+				t.Errorf("%v.At(%d, %d) equals %v, want %v", k, i, j, got, w)
+			}
+		}
+	}
+	if got := k.T().Eval(); !Equals(got, want.T()) {
+		t.Errorf("%v.T() equals %v, want %v", k, got, want.T())
+	}
+
+	// Multiplication should use the vec trick for both vectors and matrices.
+	for _, x := range []MatrixExp{GeneralRand(6, 1), GeneralRand(6, 3)} {
+		got := k.Mul(x).Eval()
+		if w := want.Mul(x).Eval(); !near(got, w, 1e-12) {
+			t.Errorf("%v.Mul(%v) equals %v, want %v", k, x, got, w)
+		}
+	}
+}
+
+func TestKronAlgebra(t *testing.T) {
+	t.Parallel()
+	a := wellConditioned(2)
+	b := wellConditioned(3)
+	k := &Kron{Left: a, Right: b}
+	dense := k.Eval()
+	for _, tt := range []struct {
+		got, want MatrixExp
+	}{
+		{got: k.Inv(), want: dense.Inv()},
+		{got: k.Scale(3), want: dense.Scale(3)},
+		{got: k.Mul(k), want: dense.Mul(dense)},
+	} {
+		if _, ok := tt.got.(*Kron); !ok {
+			t.Errorf("%v is not a Kronecker product", tt.got)
+		}
+		if got, want := tt.got.Eval(), tt.want.Eval(); !near(got, want, 1e-9) {
+			t.Errorf("%v equals %v, want %v", tt.got, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+)
+
+// LowRank represents a matrix in the factored form U * Vᵀ, where U and V have
+// the same (small) number of columns.  The algebra keeps it factored wherever
+// possible, so it is only materialized if it is evaluated directly.
+type LowRank struct {
+	U MatrixExp
+	V MatrixExp
+}
+
+// String implements the Stringer interface.
+func (m1 *LowRank) String() string {
+	return "LowRank{" + m1.U.String() + ", " + m1.V.String() + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *LowRank) Dims() (r, c int) {
+	r, _ = m1.U.Dims()
+	c, _ = m1.V.Dims()
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *LowRank) At(r, c int) float64 {
+	var v float64
+	_, k := m1.U.Dims()
+	for i := 0; i < k; i++ {
+		v += m1.U.At(r, i) * m1.V.At(c, i)
+	}
+	return v
+}
+
+// Eval returns a matrix literal.
+func (m1 *LowRank) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *LowRank) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *LowRank) evalCtx(ctx context.Context) MatrixLiteral {
+	um := evalCtx(ctx, m1.U)
+	vm := evalCtx(ctx, m1.V)
+	u := um.AsGeneral()
+	v := vm.AsGeneral()
+	m := blas64.General{
+		Rows:   u.Rows,
+		Cols:   v.Rows,
+		Stride: v.Rows,
+		Data:   getAllocator().Get(u.Rows * v.Rows),
+	}
+	blas64.Gemm(blas.NoTrans, blas.Trans, 1, u, v, 0, m)
+	release(m1.U, um)
+	release(m1.V, vm)
+	return &General{m}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *LowRank) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// leftMul multiplies the factored matrix by a matrix literal as U * (Vᵀ * X).
+func (m1 *LowRank) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	um := evalCtx(ctx, m1.U)
+	vm := evalCtx(ctx, m1.V)
+	u := um.AsGeneral()
+	v := vm.AsGeneral()
+	x := m2.AsGeneral()
+	vx := blas64.General{
+		Rows:   v.Cols,
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(v.Cols * x.Cols),
+	}
+	blas64.Gemm(blas.Trans, blas.NoTrans, 1, v, x, 0, vx)
+	m := gemm(u, vx)
+	getAllocator().Put(vx.Data)
+	release(m1.U, um)
+	release(m1.V, vm)
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *LowRank) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *LowRank) Copy() MatrixExp {
+	return &LowRank{
+		U: m1.U.Copy(),
+		V: m1.V.Copy(),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *LowRank) Err() error {
+	if err := m1.U.Err(); err != nil {
+		return err
+	}
+	if err := m1.V.Err(); err != nil {
+		return err
+	}
+
+	_, c := m1.U.Dims()
+	_, r := m1.V.Dims()
+	if c != r {
+		return ErrInnerDimMismatch{
+			R: r,
+			C: c,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.  The transpose of U * Vᵀ is V * Uᵀ.
+func (m1 *LowRank) T() MatrixExp {
+	return &LowRank{
+		U: m1.V,
+		V: m1.U,
+	}
+}
+
+// Add two matrices together.  The sum of two low rank matrices is also low
+// rank, with factors [U1 U2] * [V1 V2]ᵀ.
+func (m1 *LowRank) Add(m2 MatrixExp) MatrixExp {
+	if lr, ok := m2.(*LowRank); ok && sameDims(m1, lr) {
+		return &LowRank{
+			U: &HCat{
+				Left:  m1.U,
+				Right: lr.U,
+			},
+			V: &HCat{
+				Left:  m1.V,
+				Right: lr.V,
+			},
+		}
+	}
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.  The difference of two
+// low rank matrices is also low rank, with factors [U1 -U2] * [V1 V2]ᵀ.
+func (m1 *LowRank) Sub(m2 MatrixExp) MatrixExp {
+	if lr, ok := m2.(*LowRank); ok && sameDims(m1, lr) {
+		return &LowRank{
+			U: &HCat{
+				Left:  m1.U,
+				Right: lr.U.Scale(-1),
+			},
+			V: &HCat{
+				Left:  m1.V,
+				Right: lr.V,
+			},
+		}
+	}
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.  The scale is folded into U.
+func (m1 *LowRank) Scale(c float64) MatrixExp {
+	return &LowRank{
+		U: m1.U.Scale(c),
+		V: m1.V,
+	}
+}
+
+// Mul performs matrix multiplication.  The product is reassociated as
+// U * (Vᵀ * X) = U * (Xᵀ * V)ᵀ, which is also low rank.
+func (m1 *LowRank) Mul(m2 MatrixExp) MatrixExp {
+	_, c := m1.Dims()
+	if r, _ := m2.Dims(); c == r {
+		return &LowRank{
+			U: m1.U,
+			V: m2.T().Mul(m1.V),
+		}
+	}
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *LowRank) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *LowRank) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *LowRank) Inv() MatrixExp {
+	return &Inv{m1}
+}
+
+// sameDims determines if two matrix expressions have the same dimensions.
+func sameDims(m1, m2 MatrixExp) bool {
+	r1, c1 := m1.Dims()
+	r2, c2 := m2.Dims()
+	return r1 == r2 && c1 == c2
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLowRank(t *testing.T) {
+	t.Parallel()
+	u1 := GeneralRand(6, 2)
+	v1 := GeneralOnes(4, 2).Scale(0.5).Eval()
+	u2 := GeneralOnes(6, 1)
+	v2 := GeneralRand(4, 1)
+	x := GeneralRand(4, 3)
+	lr1 := &LowRank{U: u1, V: v1}
+	lr2 := &LowRank{U: u2, V: v2}
+	d1 := u1.Mul(v1.T())
+	d2 := u2.Mul(v2.T())
+
+	for _, tt := range []struct {
+		got, want MatrixExp
+		factored  bool
+	}{
+		{got: lr1, want: d1, factored: true},
+		{got: lr1.T(), want: d1.T(), factored: true},
+		{got: lr1.Scale(-3), want: d1.Scale(-3), factored: true},
+		{got: lr1.Mul(x), want: d1.Mul(x), factored: true},
+		{got: lr1.Add(lr2), want: d1.Add(d2), factored: true},
+		{got: lr1.Sub(lr2), want: d1.Sub(d2), factored: true},
+		{got: lr1.Add(GeneralOnes(6, 4)), want: d1.Add(GeneralOnes(6, 4)), factored: false},
+		{got: &Mul{Left: lr1, Right: x}, want: d1.Mul(x), factored: false},
+	} {
+		if err := tt.got.Err(); err != nil {
+			t.Errorf("%v.Err() equals %v, want nil", tt.got, err)
+			continue
+		}
+		if _, ok := tt.got.(*LowRank); ok != tt.factored {
+			t.Errorf("%v is factored: %v, want %v", tt.got, ok, tt.factored)
+		}
+		want := tt.want.Eval()
+		if got := tt.got.Eval(); !near(got, want, 1e-12) {
+			t.Errorf("%v equals %v, want %v", tt.got, got, want)
+		}
+		if got := tt.got.At(1, 2); math.Abs(got-want.At(1, 2)) > 1e-12 {
+			t.Errorf("%v.At(1, 2) equals %v, want %v", tt.got, got, want.At(1, 2))
+		}
+	}
+	if err := lr1.Mul(GeneralOnes(3, 3)).Err(); err == nil {
+		t.Errorf("mismatched LowRank.Mul has nil error")
+	}
+}
+
+func TestHCat(t *testing.T) {
+	t.Parallel()
+	a := GeneralRand(3, 2)
+	b := GeneralOnes(3, 4)
+	h := &HCat{Left: a, Right: b}
+	if r, c := h.Dims(); r != 3 || c != 6 {
+		t.Errorf("%v.Dims() equals (%d, %d), want (3, 6)", h, r, c)
+	}
+	m := h.Eval()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 6; j++ {
+			want := 1.0
+			if j < 2 {
+				want = a.At(i, j)
+			}
+			if got := m.At(i, j); got != want {
+				t.Errorf("%v.Eval().At(%d, %d) equals %v, want %v", h, i, j, got, want)
+			}
+			if got := h.At(i, j); got != want {
+				t.Errorf("%v.At(%d, %d) equals %v, want %v", h, i, j, got, want)
+			}
+		}
+	}
+	if err := (&HCat{Left: a, Right: GeneralOnes(2, 2)}).Err(); err == nil {
+		t.Errorf("mismatched HCat has nil error")
+	}
+}
@@ -12,6 +12,7 @@
 package matrixexp
 
 import (
+	"context"
 	"github.com/gonum/blas/blas64"
 )
 
@@ -24,8 +25,10 @@ type MatrixExp interface {
 	Dims() (r, c int)    // matrix dimensions
 	At(r, c int) float64 // get a value from a given row, column index
 
-	Eval() MatrixLiteral // Evaluates the matrix expression, producing a Matrix literal.
-	Copy() MatrixExp     // creates a (deep) copy of the matrix expression
+	Eval() MatrixLiteral                                // Evaluates the matrix expression, producing a Matrix literal.
+	EvalContext(context.Context) (MatrixLiteral, error) // Evaluates the matrix expression, unless the context is done first.
+	EvalInto(MatrixLiteral) error                       // Evaluates the matrix expression into an existing matrix literal of the same size.
+	Copy() MatrixExp                                    // creates a (deep) copy of the matrix expression
 
 	Err() error // returns the first error encountered while constructing the matrix expression.
 
@@ -42,7 +45,7 @@ type MatrixExp interface {
 	Mul(MatrixExp) MatrixExp     // matrix multiplication
 	MulElem(MatrixExp) MatrixExp // element-wise multiplication
 	DivElem(MatrixExp) MatrixExp // element-wise division
-	// Inv() MatrixExp           // matrix inversion
+	Inv() MatrixExp              // matrix inversion
 }
 
 // MatrixLiteral is a literal matrix, which can be converted to a blas64.General.
@@ -0,0 +1,215 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// NewMemo constructs a Memo of a matrix expression which is used the given
+// number of times in a larger matrix expression.
+func NewMemo(M MatrixExp, uses int) *Memo {
+	if uses < 1 {
+		uses = 1
+	}
+	return &Memo{
+		M:     M,
+		cache: &memoCache{uses: uses},
+	}
+}
+
+// Memo represents a matrix expression which is shared by several parts of a
+// larger matrix expression.  Each time the larger expression is evaluated, the
+// memo is evaluated the first time it is needed, and the result is reused
+// until every use of it has been evaluated.  Memos are normally introduced by
+// common subexpression elimination in the rewrite package.
+type Memo struct {
+	M MatrixExp
+
+	cache *memoCache
+}
+
+// memoCache identifies a memo, and any copies of its node, within an
+// evaluation.
+type memoCache struct {
+	uses int
+}
+
+// memoKey is the context key of the memos of an evaluation.
+type memoKey struct{}
+
+// memoScope holds the results of the memos in a single top-level evaluation,
+// so that a result is never reused by another evaluation, even one that
+// starts after this one was stopped early.
+type memoScope struct {
+	mu      sync.Mutex
+	entries map[*memoCache]*memoEntry
+}
+
+// memoEntry holds the result of a memo between its uses.
+type memoEntry struct {
+	once  sync.Once
+	left  int
+	m     MatrixLiteral
+	abort interface{} // the panic which stopped the evaluation, if any
+}
+
+// withMemos returns ctx with a new memo scope, unless it already has one.
+func withMemos(ctx context.Context) context.Context {
+	if s, _ := ctx.Value(memoKey{}).(*memoScope); s != nil {
+		return ctx
+	}
+	return newEvaluation(ctx)
+}
+
+// newEvaluation returns ctx with a new memo scope, which replaces any scope
+// that it already has.
+func newEvaluation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, memoKey{}, &memoScope{
+		entries: make(map[*memoCache]*memoEntry),
+	})
+}
+
+// String implements the Stringer interface.
+func (m1 *Memo) String() string {
+	return "Memo{" + m1.M.String() + ", " + strconv.Itoa(m1.cache.uses) + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Memo) Dims() (r, c int) {
+	r, c = m1.M.Dims()
+	return
+}
+
+// At returns the value at a given row, column index.  It does not use the
+// cached result.
+func (m1 *Memo) At(r, c int) float64 {
+	return m1.M.At(r, c)
+}
+
+// Eval returns a matrix literal.  The result is shared between all of the
+// uses of the memo, so it must not be modified.
+func (m1 *Memo) Eval() MatrixLiteral {
+	return detach(m1.evalCtx(background()))
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Memo) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.  The memo is evaluated
+// the first time that it is needed in the evaluation that ctx belongs to, and
+// its result is dropped once it has been used the given number of times.
+func (m1 *Memo) evalCtx(ctx context.Context) MatrixLiteral {
+	s, _ := ctx.Value(memoKey{}).(*memoScope)
+	if s == nil {
+		// The memo is evaluated on its own.
+		return evalCtx(ctx, m1.M)
+	}
+	s.mu.Lock()
+	e, ok := s.entries[m1.cache]
+	if !ok {
+		e = &memoEntry{left: m1.cache.uses}
+		s.entries[m1.cache] = e
+	}
+	if e.left--; e.left == 0 {
+		delete(s.entries, m1.cache)
+	}
+	s.mu.Unlock()
+
+	// The other uses wait for the first one to evaluate the memo.
+	e.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				e.abort = r
+				panic(r)
+			}
+		}()
+		e.m = evalCtx(ctx, m1.M)
+	})
+	if e.abort != nil {
+		panic(e.abort)
+	}
+	return e.m
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Memo) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// Copy creates a (deep) copy of the Matrix Expression.  The copy of a memo is
+// not shared, so it only has a single use.
+func (m1 *Memo) Copy() MatrixExp {
+	return NewMemo(m1.M.Copy(), 1)
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Memo) Err() error {
+	return m1.M.Err()
+}
+
+// T transposes a matrix.
+func (m1 *Memo) T() MatrixExp {
+	return &T{m1}
+}
+
+// Add two matrices together.
+func (m1 *Memo) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Memo) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Memo) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Memo) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Memo) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Memo) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Memo) Inv() MatrixExp {
+	return &Inv{m1}
+}
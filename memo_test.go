@@ -0,0 +1,48 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"testing"
+)
+
+// cancelling cancels the evaluation that it is part of.
+type cancelling struct {
+	MatrixExp
+	cancel context.CancelFunc
+}
+
+func (m1 *cancelling) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	m1.cancel()
+	return nil, context.Canceled
+}
+
+func TestMemo(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, tt := range []struct {
+		name string
+		run  func(a *General, m *Memo)
+	}{
+		{"Eval", func(a *General, m *Memo) { m.Eval() }},
+		{"cancelled EvalContext", func(a *General, m *Memo) { m.Add(&cancelling{a, cancel}).EvalContext(ctx) }},
+		{"panicking Eval", func(a *General, m *Memo) {
+			defer func() { recover() }()
+			m.Add(NewPlaceholder("x", 1, 1)).Eval()
+		}},
+	} {
+		// The memo's result from an evaluation that didn't use it every time
+		// isn't reused by the next evaluation.
+		a := &General{ones(1, 1)}
+		m := NewMemo(a.Scale(2), 2)
+		e := m.Add(m)
+		tt.run(a, m)
+		a.Data[0] = 10
+		if got := e.Eval().At(0, 0); got != 40 {
+			t.Errorf("%v.Eval() after %s equals %v, want %v", e, tt.name, got, 40)
+		}
+	}
+}
@@ -5,10 +5,23 @@
 package matrixexp
 
 import (
+	"context"
 	"github.com/gonum/blas"
 	"github.com/gonum/blas/blas64"
 )
 
+// A leftMultiplier is a structured matrix expression that can multiply a
+// matrix literal on its right without first being evaluated itself.
+type leftMultiplier interface {
+	leftMul(context.Context, MatrixLiteral) MatrixLiteral
+}
+
+// A rightMultiplier is a structured matrix expression that can multiply a
+// matrix literal on its left without first being evaluated itself.
+type rightMultiplier interface {
+	rightMul(context.Context, MatrixLiteral) MatrixLiteral
+}
+
 // Mul represents matrix multiplication.
 type Mul struct {
 	Left  MatrixExp
@@ -39,26 +52,85 @@ func (m1 *Mul) At(r, c int) float64 {
 
 // Eval returns a matrix literal.
 func (m1 *Mul) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Mul) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Mul) evalCtx(ctx context.Context) MatrixLiteral {
 
 	// This should be replaced with a call to Eval on each side, and then a type
 	// switch to handle the various matrix literals.
 
-	lm := m1.Left.Eval()
-	rm := m1.Right.Eval()
+	if lmul, ok := m1.Left.(leftMultiplier); ok {
+		rm := evalCtx(ctx, m1.Right)
+		m := lmul.leftMul(ctx, rm)
+		release(m1.Right, rm)
+		return m
+	}
+	if rmul, ok := m1.Right.(rightMultiplier); ok {
+		lm := evalCtx(ctx, m1.Left)
+		m := rmul.rightMul(ctx, lm)
+		release(m1.Left, lm)
+		return m
+	}
+
+	r, c := m1.Dims()
+	m := newGeneral(r, c)
+	m1.evalIntoCtx(ctx, m)
+	return m
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Mul) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *Mul) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	if err := checkDst(m1, dst); err != nil {
+		return err
+	}
+	d := dst.AsGeneral()
+
+	_, lok := m1.Left.(leftMultiplier)
+	_, rok := m1.Right.(rightMultiplier)
+	if lok || rok {
+		// Structured products always produce their own result.
+		m := m1.evalCtx(ctx)
+		copyInto(d, m.AsGeneral())
+		release(m1, m)
+		return nil
+	}
 
+	lm := evalCtx(ctx, m1.Left)
+	rm := evalCtx(ctx, m1.Right)
 	left := lm.AsGeneral()
 	right := rm.AsGeneral()
-	r, c := m1.Dims()
-	m := blas64.General{
-		Rows:   r,
-		Cols:   c,
-		Stride: c,
-		Data:   make([]float64, r*c),
+	if sameArray(d.Data, left.Data) || sameArray(d.Data, right.Data) {
+		// Gemm can't write into one of its own operands.
+		m := newGeneral(d.Rows, d.Cols)
+		blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, left, right, 0, m.General)
+		copyInto(d, m.General)
+		release(m1, m)
+	} else {
+		blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, left, right, 0, d)
 	}
-	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, left, right, 0, m)
-	return &General{m}
+	release(m1.Left, lm)
+	release(m1.Right, rm)
+	return nil
 }
 
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Mul) ownedResult() {}
+
 // Copy creates a (deep) copy of the Matrix Expression.
 func (m1 *Mul) Copy() MatrixExp {
 	return &Mul{
@@ -139,3 +211,8 @@ func (m1 *Mul) DivElem(m2 MatrixExp) MatrixExp {
 		Right: m2,
 	}
 }
+
+// Inv inverts a matrix.
+func (m1 *Mul) Inv() MatrixExp {
+	return &Inv{m1}
+}
@@ -0,0 +1,225 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+)
+
+// Outer represents the outer product U * Vᵀ of two column vectors.
+type Outer struct {
+	U MatrixExp
+	V MatrixExp
+}
+
+// String implements the Stringer interface.
+func (m1 *Outer) String() string {
+	return "Outer{" + m1.U.String() + ", " + m1.V.String() + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Outer) Dims() (r, c int) {
+	r, _ = m1.U.Dims()
+	c, _ = m1.V.Dims()
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Outer) At(r, c int) float64 {
+	return m1.U.At(r, 0) * m1.V.At(c, 0)
+}
+
+// Eval returns a matrix literal.
+func (m1 *Outer) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Outer) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Outer) evalCtx(ctx context.Context) MatrixLiteral {
+	r, c := m1.Dims()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   c,
+		Stride: c,
+		Data:   getAllocator().Get(r * c),
+	}
+	um := evalCtx(ctx, m1.U)
+	vm := evalCtx(ctx, m1.V)
+	blas64.Ger(1, colVector(um.AsGeneral()), colVector(vm.AsGeneral()), m)
+	release(m1.U, um)
+	release(m1.V, vm)
+	return &General{m}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Outer) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *Outer) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	if err := checkDst(m1, dst); err != nil {
+		return err
+	}
+	d := dst.AsGeneral()
+	um := evalCtx(ctx, m1.U)
+	vm := evalCtx(ctx, m1.V)
+	u := disjoint(d, um.AsGeneral())
+	v := disjoint(d, vm.AsGeneral())
+	for i := 0; i < d.Rows; i++ {
+		row := d.Data[i*d.Stride : i*d.Stride+d.Cols]
+		for j := range row {
+			row[j] = 0
+		}
+	}
+	blas64.Ger(1, colVector(u), colVector(v), d)
+	release(m1.U, um)
+	release(m1.V, vm)
+	return nil
+}
+
+// leftMul multiplies the outer product by a matrix literal as U * (Xᵀ V)ᵀ, so
+// that the outer product is never formed.
+func (m1 *Outer) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	r, _ := m1.Dims()
+	x := m2.AsGeneral()
+	w := blas64.Vector{
+		Inc:  1,
+		Data: getAllocator().Get(x.Cols),
+	}
+	vm := evalCtx(ctx, m1.V)
+	blas64.Gemv(blas.Trans, 1, x, colVector(vm.AsGeneral()), 0, w)
+	release(m1.V, vm)
+	m := blas64.General{
+		Rows:   r,
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(r * x.Cols),
+	}
+	um := evalCtx(ctx, m1.U)
+	blas64.Ger(1, colVector(um.AsGeneral()), w, m)
+	release(m1.U, um)
+	getAllocator().Put(w.Data)
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Outer) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Outer) Copy() MatrixExp {
+	return &Outer{
+		U: m1.U.Copy(),
+		V: m1.V.Copy(),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Outer) Err() error {
+	if err := m1.U.Err(); err != nil {
+		return err
+	}
+	if err := m1.V.Err(); err != nil {
+		return err
+	}
+	if r, c := m1.U.Dims(); c != 1 {
+		return ErrNotVector{
+			R: r,
+			C: c,
+		}
+	}
+	if r, c := m1.V.Dims(); c != 1 {
+		return ErrNotVector{
+			R: r,
+			C: c,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.  The transpose of U * Vᵀ is V * Uᵀ.
+func (m1 *Outer) T() MatrixExp {
+	return &Outer{
+		U: m1.V,
+		V: m1.U,
+	}
+}
+
+// Add two matrices together.  Adding an outer product to a matrix is a rank
+// one update.
+func (m1 *Outer) Add(m2 MatrixExp) MatrixExp {
+	return &Ger{
+		A:     m2,
+		Alpha: 1,
+		U:     m1.U,
+		V:     m1.V,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.  Subtracting a matrix
+// from an outer product is a rank one update of the negated matrix.
+func (m1 *Outer) Sub(m2 MatrixExp) MatrixExp {
+	return &Ger{
+		A:     m2.Scale(-1),
+		Alpha: 1,
+		U:     m1.U,
+		V:     m1.V,
+	}
+}
+
+// Scale performs scalar multiplication.  The scale is folded into U.
+func (m1 *Outer) Scale(c float64) MatrixExp {
+	return &Outer{
+		U: m1.U.Scale(c),
+		V: m1.V,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Outer) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Outer) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Outer) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Outer) Inv() MatrixExp {
+	return &Inv{m1}
+}
+
+// colVector returns a column vector as a blas64.Vector, without copying.
+func colVector(g blas64.General) blas64.Vector {
+	return blas64.Vector{
+		Inc:  g.Stride,
+		Data: g.Data,
+	}
+}
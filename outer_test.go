@@ -0,0 +1,64 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOuter(t *testing.T) {
+	t.Parallel()
+	u := GeneralRand(5, 1)
+	v := GeneralOnes(3, 1).Scale(2).Eval()
+	a := GeneralRand(5, 3)
+	for _, tt := range []struct {
+		got, want MatrixExp
+	}{
+		{got: &Outer{U: u, V: v}, want: u.Mul(v.T())},
+		{got: (&Outer{U: u, V: v}).T(), want: v.Mul(u.T())},
+		{got: (&Outer{U: u, V: v}).Scale(3), want: u.Mul(v.T()).Scale(3)},
+		{got: (&Outer{U: u, V: v}).Mul(a.T()), want: u.Mul(v.T()).Mul(a.T())},
+		{got: (&Outer{U: u, V: v}).Add(a), want: a.Add(u.Mul(v.T()))},
+		{got: (&Outer{U: u, V: v}).Sub(a), want: u.Mul(v.T()).Sub(a)},
+		{got: &Ger{A: a, Alpha: -0.5, U: u, V: v}, want: a.Add(u.Mul(v.T()).Scale(-0.5))},
+		{got: (&Ger{A: a, Alpha: -0.5, U: u, V: v}).T(), want: a.Add(u.Mul(v.T()).Scale(-0.5)).T()},
+		{got: (&Ger{A: a, Alpha: -0.5, U: u, V: v}).Scale(4), want: a.Add(u.Mul(v.T()).Scale(-0.5)).Scale(4)},
+	} {
+		if err := tt.got.Err(); err != nil {
+			t.Errorf("%v.Err() equals %v, want nil", tt.got, err)
+			continue
+		}
+		want := tt.want.Eval()
+		if got := tt.got.Eval(); !near(got, want, 1e-12) {
+			t.Errorf("%v equals %v, want %v", tt.got, got, want)
+		}
+		r, c := want.Dims()
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if got := tt.got.At(i, j); math.Abs(got-want.At(i, j)) > 1e-12 {
+					t.Errorf("%v.At(%d, %d) equals %v, want %v", tt.got, i, j, got, want.At(i, j))
+				}
+			}
+		}
+	}
+}
+
+func TestOuterErr(t *testing.T) {
+	t.Parallel()
+	u := GeneralRand(5, 1)
+	for _, tt := range []struct {
+		m   MatrixExp
+		err error
+	}{
+		{m: &Outer{U: GeneralOnes(5, 2), V: u}, err: ErrNotVector{R: 5, C: 2}},
+		{m: &Outer{U: u, V: GeneralOnes(1, 5)}, err: ErrNotVector{R: 1, C: 5}},
+		{m: &Ger{A: GeneralOnes(5, 4), Alpha: 1, U: u, V: u}, err: ErrDimMismatch{R1: 5, C1: 4, R2: 5, C2: 5}},
+	} {
+		if err := tt.m.Err(); err != tt.err {
+			t.Errorf("%v.Err() equals %v, want %v", tt.m, err, tt.err)
+		}
+	}
+}
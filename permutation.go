@@ -0,0 +1,197 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"fmt"
+	"github.com/gonum/blas/blas64"
+)
+
+// Permutation represents a permutation matrix.  Row i of the matrix has a one
+// in column Index[i], so multiplying by it on the left gathers rows, and
+// multiplying by it on the right gathers columns, in O(n) per row or column.
+type Permutation struct {
+	Index []int
+}
+
+// String implements the Stringer interface.
+func (m1 *Permutation) String() string {
+	return fmt.Sprintf("Permutation{%v}", m1.Index)
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Permutation) Dims() (r, c int) {
+	r, c = len(m1.Index), len(m1.Index)
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Permutation) At(r, c int) float64 {
+	if m1.Index[r] == c {
+		return 1
+	}
+	return 0
+}
+
+// Eval returns a matrix literal.
+func (m1 *Permutation) Eval() MatrixLiteral {
+	n := len(m1.Index)
+	m := blas64.General{
+		Rows:   n,
+		Cols:   n,
+		Stride: n,
+		Data:   getAllocator().Get(n * n),
+	}
+	for i, j := range m1.Index {
+		m.Data[i*n+j] = 1
+	}
+	return &General{m}
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Permutation) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Permutation) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// leftMul gathers the rows of a matrix literal.
+func (m1 *Permutation) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	x := m2.AsGeneral()
+	m := blas64.General{
+		Rows:   len(m1.Index),
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(len(m1.Index) * x.Cols),
+	}
+	for i, j := range m1.Index {
+		copy(m.Data[i*m.Stride:i*m.Stride+m.Cols], x.Data[j*x.Stride:j*x.Stride+x.Cols])
+	}
+	return &General{m}
+}
+
+// rightMul gathers the columns of a matrix literal.
+func (m1 *Permutation) rightMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	x := m2.AsGeneral()
+	m := blas64.General{
+		Rows:   x.Rows,
+		Cols:   len(m1.Index),
+		Stride: len(m1.Index),
+		Data:   getAllocator().Get(x.Rows * len(m1.Index)),
+	}
+	for i := 0; i < x.Rows; i++ {
+		row := m.Data[i*m.Stride : i*m.Stride+m.Cols]
+		xrow := x.Data[i*x.Stride : i*x.Stride+x.Cols]
+		for k, j := range m1.Index {
+			row[j] = xrow[k]
+		}
+	}
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Permutation) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Permutation) Copy() MatrixExp {
+	idx := make([]int, len(m1.Index))
+	copy(idx, m1.Index)
+	return &Permutation{idx}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Permutation) Err() error {
+	seen := make([]bool, len(m1.Index))
+	for i, j := range m1.Index {
+		if j < 0 || j >= len(seen) || seen[j] {
+			return ErrInvalidPermutation{
+				I:     i,
+				Index: j,
+			}
+		}
+		seen[j] = true
+	}
+	return nil
+}
+
+// T transposes a matrix.  The transpose of a permutation is its inverse.
+func (m1 *Permutation) T() MatrixExp {
+	if m1.Err() != nil {
+		return &T{m1}
+	}
+	inv := make([]int, len(m1.Index))
+	for i, j := range m1.Index {
+		inv[j] = i
+	}
+	return &Permutation{inv}
+}
+
+// Add two matrices together.
+func (m1 *Permutation) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Permutation) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Permutation) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.  The product of two valid permutations
+// is a permutation.
+func (m1 *Permutation) Mul(m2 MatrixExp) MatrixExp {
+	if p, ok := m2.(*Permutation); ok && len(p.Index) == len(m1.Index) && m1.Err() == nil && p.Err() == nil {
+		idx := make([]int, len(m1.Index))
+		for i, j := range m1.Index {
+			idx[i] = p.Index[j]
+		}
+		return &Permutation{idx}
+	}
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Permutation) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Permutation) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.  The inverse of a permutation is its transpose.
+func (m1 *Permutation) Inv() MatrixExp {
+	return m1.T()
+}
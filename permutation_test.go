@@ -0,0 +1,74 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"testing"
+)
+
+func TestPermutation(t *testing.T) {
+	t.Parallel()
+	p := &Permutation{Index: []int{2, 0, 3, 1}}
+	q := &Permutation{Index: []int{1, 3, 0, 2}}
+	dp := p.Eval()
+	dq := q.Eval()
+	x := GeneralRand(4, 3)
+	y := GeneralRand(2, 4)
+
+	for _, tt := range []struct {
+		got, want MatrixExp
+	}{
+		{got: p.Mul(x), want: dp.Mul(x)},
+		{got: y.Mul(p), want: y.Mul(dp)},
+		{got: p.T(), want: dp.T()},
+		{got: p.Inv(), want: dp.T()},
+		{got: p.Mul(q), want: dp.Mul(dq)},
+		{got: p.Mul(p.Inv()), want: &General{eye(4)}},
+	} {
+		if err := tt.got.Err(); err != nil {
+			t.Errorf("%v.Err() equals %v, want nil", tt.got, err)
+		}
+		if got, want := tt.got.Eval(), tt.want.Eval(); !Equals(got, want) {
+			t.Errorf("%v equals %v, want %v", tt.got, got, want)
+		}
+	}
+	for _, m := range []MatrixExp{p.T(), p.Inv(), p.Mul(q)} {
+		if _, ok := m.(*Permutation); !ok {
+			t.Errorf("%v is not a Permutation", m)
+		}
+	}
+}
+
+func TestPermutationErr(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		p   *Permutation
+		err error
+	}{
+		{p: &Permutation{Index: []int{}}, err: nil},
+		{p: &Permutation{Index: []int{1, 0}}, err: nil},
+		{p: &Permutation{Index: []int{0, 0}}, err: ErrInvalidPermutation{I: 1, Index: 0}},
+		{p: &Permutation{Index: []int{0, 2}}, err: ErrInvalidPermutation{I: 1, Index: 2}},
+		{p: &Permutation{Index: []int{-1, 0}}, err: ErrInvalidPermutation{I: 0, Index: -1}},
+	} {
+		if err := tt.p.Err(); err != tt.err {
+			t.Errorf("%v.Err() equals %v, want %v", tt.p, err, tt.err)
+		}
+		if err := tt.p.T().Err(); err != tt.err {
+			t.Errorf("%v.T().Err() equals %v, want %v", tt.p, err, tt.err)
+		}
+		// Malformed permutations aren't composed.
+		id := &Permutation{Index: make([]int, len(tt.p.Index))}
+		for i := range id.Index {
+			id.Index[i] = i
+		}
+		if err := id.Mul(tt.p).Err(); err != tt.err {
+			t.Errorf("%v.Mul(%v).Err() equals %v, want %v", id, tt.p, err, tt.err)
+		}
+		if err := tt.p.Mul(id).Err(); err != tt.err {
+			t.Errorf("%v.Mul(%v).Err() equals %v, want %v", tt.p, id, err, tt.err)
+		}
+	}
+}
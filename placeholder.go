@@ -0,0 +1,243 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+)
+
+// NewPlaceholder constructs an unbound Placeholder for a matrix with the given
+// name and dimensions.
+func NewPlaceholder(name string, r, c int) *Placeholder {
+	return &Placeholder{
+		Name: name,
+		R:    r,
+		C:    c,
+	}
+}
+
+// Placeholder represents a named matrix which is only supplied when the
+// expression is evaluated, so that an expression can be built and compiled
+// once and then evaluated many times with different inputs.  Bind replaces
+// the placeholders in an expression with bound copies of them.  An unbound
+// placeholder, or one bound to a matrix with different dimensions, reports
+// the problem from Err, and panics with it if it is evaluated.
+type Placeholder struct {
+	Name string
+	R, C int
+
+	value MatrixLiteral
+}
+
+// String implements the Stringer interface.
+func (m1 *Placeholder) String() string {
+	return "Placeholder{" + strconv.Quote(m1.Name) + ", " + strconv.Itoa(m1.R) + ", " + strconv.Itoa(m1.C) + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Placeholder) Dims() (r, c int) {
+	r = m1.R
+	c = m1.C
+	return
+}
+
+// bound returns the matrix which the placeholder is bound to, or panics if it
+// can't be used.
+func (m1 *Placeholder) bound() MatrixLiteral {
+	if err := m1.Err(); err != nil {
+		panic(err)
+	}
+	return m1.value
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Placeholder) At(r, c int) float64 {
+	return m1.bound().At(r, c)
+}
+
+// Eval returns a matrix literal, which is the bound matrix itself.
+func (m1 *Placeholder) Eval() MatrixLiteral {
+	return m1.bound()
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Placeholder) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Placeholder) EvalInto(dst MatrixLiteral) error {
+	if err := m1.Err(); err != nil {
+		return err
+	}
+	return evalInto(background(), m1, dst)
+}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Placeholder) Copy() MatrixExp {
+	var v MatrixLiteral
+	if m1.value != nil {
+		v = m1.value.Copy().Eval()
+	}
+	return &Placeholder{
+		Name:  m1.Name,
+		R:     m1.R,
+		C:     m1.C,
+		value: v,
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Placeholder) Err() error {
+	if m1.R < 0 {
+		return ErrInvalidRows(m1.R)
+	}
+	if m1.C < 0 {
+		return ErrInvalidCols(m1.C)
+	}
+	if m1.value == nil {
+		return ErrUnbound(m1.Name)
+	}
+	if err := m1.value.Err(); err != nil {
+		return err
+	}
+	if r, c := m1.value.Dims(); r != m1.R || c != m1.C {
+		return ErrBindingDims{
+			Name: m1.Name,
+			R:    m1.R,
+			C:    m1.C,
+			BR:   r,
+			BC:   c,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *Placeholder) T() MatrixExp {
+	return &T{m1}
+}
+
+// Add two matrices together.
+func (m1 *Placeholder) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Placeholder) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Placeholder) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Placeholder) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Placeholder) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Placeholder) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Placeholder) Inv() MatrixExp {
+	return &Inv{m1}
+}
+
+// Bind returns a copy of a matrix expression where each Placeholder is bound
+// to the matrix with the same name in bindings.  Placeholders without a
+// binding are left unbound.  The matrices are not copied, so they must not be
+// changed while the expression is being evaluated.  The expression itself is
+// not modified, so it can be bound many times, and the bound expressions can
+// be evaluated concurrently.
+func Bind(m MatrixExp, bindings map[string]MatrixLiteral) MatrixExp {
+	b := &binder{
+		bindings: bindings,
+		done:     make(map[MatrixExp]MatrixExp),
+	}
+	return b.bind(m)
+}
+
+// EvalWith binds the placeholders in a matrix expression (see Bind) and then
+// evaluates it, or returns the first error in the bound expression, such as a
+// missing or mis-shaped binding.
+func EvalWith(m MatrixExp, bindings map[string]MatrixLiteral) (MatrixLiteral, error) {
+	bm := Bind(m, bindings)
+	if err := bm.Err(); err != nil {
+		return nil, err
+	}
+	return bm.Eval(), nil
+}
+
+// binder holds the state of a call to Bind.
+type binder struct {
+	bindings map[string]MatrixLiteral
+	done     map[MatrixExp]MatrixExp // so that shared nodes stay shared
+}
+
+// bind returns a bound copy of m.  Every node is copied, including the ones
+// without placeholders below them, so that Memos aren't shared with the
+// original expression.
+func (b *binder) bind(m MatrixExp) MatrixExp {
+	if !structNode(m) {
+		return m
+	}
+	if bm, ok := b.done[m]; ok {
+		return bm
+	}
+	var bm MatrixExp
+	switch m1 := m.(type) {
+	case *Placeholder:
+		bm = &Placeholder{
+			Name:  m1.Name,
+			R:     m1.R,
+			C:     m1.C,
+			value: b.bindings[m1.Name],
+		}
+	case *Memo:
+		bm = NewMemo(b.bind(m1.M), m1.cache.uses)
+	default:
+		r := reflect.ValueOf(m).Elem()
+		cp := reflect.New(r.Type())
+		cp.Elem().Set(r)
+		forOperands(m, func(name string, child MatrixExp) {
+			cp.Elem().FieldByName(name).Set(reflect.ValueOf(b.bind(child)))
+		})
+		bm = cp.Interface().(MatrixExp)
+	}
+	b.done[m] = bm
+	return bm
+}
@@ -0,0 +1,60 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import "testing"
+
+func TestPlaceholder(t *testing.T) {
+	t.Parallel()
+	// The covariance update of a Kalman filter.
+	h := &General{rnd(2, 3)}
+	r := &General{eye(2)}
+	P := NewPlaceholder("P", 3, 3)
+	s := NewMemo(h.Mul(P).Mul(h.T()).Add(r), 3)
+	m := P.Sub(P.Mul(h.T()).Mul(s.Inv()).Mul(h).Mul(P)).Add(h.T().Mul(s.Sub(s)).Mul(h))
+
+	for i := 0; i < 3; i++ {
+		p := wellConditioned(3).Scale(float64(i + 1)).Eval()
+		s := h.Mul(p).Mul(h.T()).Add(r)
+		want := p.Sub(p.Mul(h.T()).Mul(s.Inv()).Mul(h).Mul(p)).Eval()
+		got, err := EvalWith(m, map[string]MatrixLiteral{"P": p})
+		if err != nil {
+			t.Fatalf("EvalWith(%v) returned error %v", m, err)
+		}
+		if !EqualsApprox(got, want, Tolerance{Tol: 1e-9}) {
+			t.Errorf("EvalWith(%v) equals %v, want %v", m, got, want)
+		}
+	}
+
+	// The original expression is still unbound.
+	if err := m.Err(); err != ErrUnbound("P") {
+		t.Errorf("%v.Err() equals %v, want %v", m, err, ErrUnbound("P"))
+	}
+	if _, err := EvalWith(m, nil); err != ErrUnbound("P") {
+		t.Errorf("EvalWith(%v, nil) returned error %v, want %v", m, err, ErrUnbound("P"))
+	}
+	bad := map[string]MatrixLiteral{"P": &General{rnd(2, 3)}}
+	want := ErrBindingDims{Name: "P", R: 3, C: 3, BR: 2, BC: 3}
+	if err := Bind(m, bad).Err(); err != want {
+		t.Errorf("Bind(%v).Err() equals %v, want %v", m, err, want)
+	}
+}
+
+func TestBindShared(t *testing.T) {
+	t.Parallel()
+	x := NewPlaceholder("x", 4, 4)
+	y := x.Mul(x)
+	m := y.Add(y.T())
+	b := Bind(m, map[string]MatrixLiteral{"x": &General{rnd(4, 4)}}).(*Add)
+	if b.Left != b.Right.(*T).M {
+		t.Errorf("Bind(%v) doesn't share %v", m, y)
+	}
+	if b.Left == y {
+		t.Errorf("Bind(%v) didn't copy %v", m, y)
+	}
+	if x.Err() != ErrUnbound("x") {
+		t.Errorf("Bind(%v) bound the original placeholder", m)
+	}
+}
@@ -0,0 +1,518 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+	"reflect"
+	"strconv"
+)
+
+// Execution plans.  Plan lowers a matrix expression into a Program, which is a
+// flat list of instructions over numbered slots.  Each slot holds a matrix:
+// either an input, which is a literal from the expression or the binding of a
+// placeholder, or a temporary, which is the result of an instruction.  The
+// temporaries are allocated once, when the plan is made, and temporaries
+// which are never live at the same time share a buffer.  Operations without an
+// instruction of their own, like Inv or structured matrices, are evaluated by
+// an Eval instruction on a copy of the node whose operands are the slots.
+
+// Op is the operation performed by an instruction.
+type Op int
+
+// The operations that a Program can perform.
+const (
+	OpCopy      Op = iota // Dst = Args[0]
+	OpGemm                // Dst = Alpha * op(Args[0]) * op(Args[1])
+	OpAxpy                // Dst += Alpha * Args[0]
+	OpScale               // Dst = Alpha * Args[0]
+	OpTranspose           // Dst = Args[0].T()
+	OpMulElem             // Dst = Args[0] .* Args[1]
+	OpDivElem             // Dst = Args[0] ./ Args[1]
+	OpEval                // Dst = Exp, with operands read from Args
+)
+
+var opNames = [...]string{
+	OpCopy:      "copy",
+	OpGemm:      "gemm",
+	OpAxpy:      "axpy",
+	OpScale:     "scale",
+	OpTranspose: "transpose",
+	OpMulElem:   "mulelem",
+	OpDivElem:   "divelem",
+	OpEval:      "eval",
+}
+
+func (o Op) String() string {
+	if o < 0 || int(o) >= len(opNames) {
+		return "Op(" + strconv.Itoa(int(o)) + ")"
+	}
+	return opNames[o]
+}
+
+// Instr is a single instruction of a Program.
+type Instr struct {
+	Op    Op
+	Dst   int
+	Args  []int
+	Alpha float64
+
+	// TransA and TransB transpose the operands of a Gemm.
+	TransA, TransB bool
+
+	// Exp is the expression evaluated by an Eval, whose operands are the
+	// slots in Args.
+	Exp MatrixExp
+}
+
+// String implements the Stringer interface.
+func (in Instr) String() string {
+	arg := func(i int) string {
+		s := "s" + strconv.Itoa(in.Args[i])
+		if (i == 0 && in.TransA) || (i == 1 && in.TransB) {
+			s += ".T"
+		}
+		return s
+	}
+	args := make([]string, len(in.Args))
+	for i := range in.Args {
+		args[i] = arg(i)
+	}
+	dst := "s" + strconv.Itoa(in.Dst)
+	switch in.Op {
+	case OpGemm, OpScale:
+		return fmt.Sprintf("%s = %v(%v, %s)", dst, in.Op, in.Alpha, join(args))
+	case OpAxpy:
+		return fmt.Sprintf("%s = %v(%v, %s, %s)", dst, in.Op, in.Alpha, join(args), dst)
+	case OpEval:
+		return fmt.Sprintf("%s = %v(%s, %s)", dst, in.Op, reflect.Indirect(reflect.ValueOf(in.Exp)).Type().Name(), join(args))
+	}
+	return fmt.Sprintf("%s = %v(%s)", dst, in.Op, join(args))
+}
+
+// join joins the names of slots with commas.
+func join(args []string) string {
+	var b bytes.Buffer
+	for i, a := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(a)
+	}
+	return b.String()
+}
+
+// Slot is a matrix used by a Program.
+type Slot struct {
+	Rows, Cols int
+
+	// Input is true for slots that aren't computed by the program.  They are
+	// either literals, or placeholders with a Name.
+	Input bool
+	Name  string
+
+	// Buffer is the index of the buffer holding a temporary, or -1 for an
+	// input.
+	Buffer int
+
+	view *General // the matrix itself, which the instructions use
+	ph   bool     // the slot is a placeholder
+}
+
+// Program is an execution plan for a matrix expression, which can be executed
+// repeatedly with different bindings of the placeholders in the expression.
+// A Program is not safe for concurrent use.
+type Program struct {
+	Instrs  []Instr
+	Slots   []Slot
+	Result  int
+	Buffers []int // the length of each buffer
+
+	exp MatrixExp
+}
+
+// Plan lowers a matrix expression, such as one that has been compiled by the
+// rewrite package, into a Program.  Products become calls to Gemm, with any
+// transposes and scaling of them folded in, sums and differences become a
+// copy and an axpy, and the other element-wise operations and transposes
+// become loops.  Memos and shared nodes are only evaluated once.  The
+// placeholders in the expression don't have to be bound, but any other error
+// in the expression is returned.
+func Plan(m MatrixExp) (*Program, error) {
+	pl := &planner{
+		p:    &Program{exp: m},
+		done: make(map[MatrixExp]int),
+	}
+	res := pl.lower(m)
+	if pl.p.Slots[res].Input {
+		// The program always writes its result into a temporary.
+		in := res
+		res = pl.temp(m.Dims())
+		pl.emit(Instr{Op: OpCopy, Dst: res, Args: []int{in}})
+	}
+	pl.p.Result = res
+	if err := Bind(m, pl.zeros()).Err(); err != nil {
+		return nil, err
+	}
+	pl.allocate()
+	return pl.p, nil
+}
+
+// planner holds the state of a call to Plan.
+type planner struct {
+	p    *Program
+	done map[MatrixExp]int // the slot holding each node that has been lowered
+}
+
+// slot adds a slot to the program.
+func (pl *planner) slot(s Slot) int {
+	s.view = &General{blas64.General{Rows: s.Rows, Cols: s.Cols, Stride: s.Cols}}
+	pl.p.Slots = append(pl.p.Slots, s)
+	return len(pl.p.Slots) - 1
+}
+
+// temp adds a temporary slot to the program.
+func (pl *planner) temp(r, c int) int {
+	return pl.slot(Slot{Rows: r, Cols: c, Buffer: -1})
+}
+
+// emit adds an instruction to the program.
+func (pl *planner) emit(in Instr) {
+	pl.p.Instrs = append(pl.p.Instrs, in)
+}
+
+// lower adds the instructions which evaluate m to the program, and returns
+// the slot that holds the result.
+func (pl *planner) lower(m MatrixExp) int {
+	m = unwrap(m)
+	if s, ok := pl.done[m]; ok {
+		return s
+	}
+	r, c := m.Dims()
+	var s int
+	switch m1 := m.(type) {
+	case *Placeholder:
+		s = pl.slot(Slot{Rows: r, Cols: c, Input: true, Name: m1.Name, Buffer: -1, ph: true})
+	case MatrixLiteral:
+		s = pl.slot(Slot{Rows: r, Cols: c, Input: true, Buffer: -1})
+		pl.p.Slots[s].view.General = m1.AsGeneral()
+	case *Mul:
+		if s, ok := pl.gemm(m1, 1); ok {
+			pl.done[m] = s
+			return s
+		}
+		s = pl.eval(m1)
+	case *Scale:
+		if mul, ok := unwrap(m1.M).(*Mul); ok {
+			if s, ok := pl.gemm(mul, m1.C); ok {
+				pl.done[m] = s
+				return s
+			}
+		}
+		x := pl.lower(m1.M)
+		s = pl.temp(r, c)
+		pl.emit(Instr{Op: OpScale, Dst: s, Args: []int{x}, Alpha: m1.C})
+	case *Add:
+		s = pl.axpy(m1.Left, m1.Right, 1)
+	case *Sub:
+		s = pl.axpy(m1.Left, m1.Right, -1)
+	case *MulElem:
+		a, b := pl.lower(m1.Left), pl.lower(m1.Right)
+		s = pl.temp(r, c)
+		pl.emit(Instr{Op: OpMulElem, Dst: s, Args: []int{a, b}})
+	case *DivElem:
+		a, b := pl.lower(m1.Left), pl.lower(m1.Right)
+		s = pl.temp(r, c)
+		pl.emit(Instr{Op: OpDivElem, Dst: s, Args: []int{a, b}})
+	case *T:
+		x := pl.lower(m1.M)
+		s = pl.temp(r, c)
+		pl.emit(Instr{Op: OpTranspose, Dst: s, Args: []int{x}})
+	default:
+		s = pl.eval(m)
+	}
+	pl.done[m] = s
+	return s
+}
+
+// gemm lowers a scaled product to a Gemm, unless it is a structured product.
+func (pl *planner) gemm(m *Mul, alpha float64) (int, bool) {
+	_, lok := m.Left.(leftMultiplier)
+	_, rok := m.Right.(rightMultiplier)
+	if lok || rok {
+		return 0, false
+	}
+	a, ta := pl.lowerT(m.Left)
+	b, tb := pl.lowerT(m.Right)
+	s := pl.temp(m.Dims())
+	pl.emit(Instr{Op: OpGemm, Dst: s, Args: []int{a, b}, Alpha: alpha, TransA: ta, TransB: tb})
+	return s, true
+}
+
+// lowerT lowers an operand of a product, and determines if it is transposed,
+// so that the transpose can be done by Gemm.
+func (pl *planner) lowerT(m MatrixExp) (int, bool) {
+	if t, ok := unwrap(m).(*T); ok {
+		if _, ok := pl.done[t]; !ok {
+			return pl.lower(t.M), true
+		}
+	}
+	return pl.lower(m), false
+}
+
+// axpy lowers a sum or difference to a copy followed by an axpy.
+func (pl *planner) axpy(left, right MatrixExp, alpha float64) int {
+	a, b := pl.lower(left), pl.lower(right)
+	s := pl.temp(pl.p.Slots[a].Rows, pl.p.Slots[a].Cols)
+	pl.emit(Instr{Op: OpCopy, Dst: s, Args: []int{a}})
+	pl.emit(Instr{Op: OpAxpy, Dst: s, Args: []int{b}, Alpha: alpha})
+	return s
+}
+
+// eval lowers m to an Eval of a copy of it whose operands are slots.
+func (pl *planner) eval(m MatrixExp) int {
+	exp, args := pl.operands(m)
+	s := pl.temp(m.Dims())
+	pl.emit(Instr{Op: OpEval, Dst: s, Args: args, Exp: exp})
+	return s
+}
+
+// operands returns a copy of m whose operands are replaced by the slots that
+// hold them, along with the slots.  Structured operands of products are kept,
+// with their own operands replaced, so that they are never formed.
+func (pl *planner) operands(m MatrixExp) (MatrixExp, []int) {
+	if !structNode(m) {
+		return m, nil
+	}
+	var args []int
+	r := reflect.ValueOf(m).Elem()
+	cp := reflect.New(r.Type())
+	cp.Elem().Set(r)
+	forOperands(m, func(name string, child MatrixExp) {
+		var op MatrixExp
+		if inlined(m, name, child) {
+			var a []int
+			op, a = pl.operands(child)
+			args = append(args, a...)
+		} else {
+			s := pl.lower(child)
+			op = pl.p.Slots[s].view
+			args = append(args, s)
+		}
+		cp.Elem().FieldByName(name).Set(reflect.ValueOf(op))
+	})
+	return cp.Interface().(MatrixExp), args
+}
+
+// zeros returns bindings of each placeholder to a matrix of zeros, so that the
+// rest of the expression can be checked for errors.
+func (pl *planner) zeros() map[string]MatrixLiteral {
+	b := make(map[string]MatrixLiteral)
+	for _, s := range pl.p.Slots {
+		if s.ph {
+			if _, ok := b[s.Name]; !ok {
+				b[s.Name] = &General{blas64.General{
+					Rows:   s.Rows,
+					Cols:   s.Cols,
+					Stride: s.Cols,
+					Data:   make([]float64, s.Rows*s.Cols),
+				}}
+			}
+		}
+	}
+	return b
+}
+
+// allocate assigns a buffer to each temporary.  A buffer is reused once the
+// last instruction that refers to the temporary in it has been executed, by
+// the next temporary of the same size.
+func (pl *planner) allocate() {
+	p := pl.p
+	last := make([]int, len(p.Slots))
+	for i, in := range p.Instrs {
+		last[in.Dst] = i
+		for _, a := range in.Args {
+			last[a] = i
+		}
+	}
+	last[p.Result] = len(p.Instrs)
+
+	free := make(map[int][]int) // buffers by length
+	for i, in := range p.Instrs {
+		if d := &p.Slots[in.Dst]; d.Buffer < 0 {
+			n := d.Rows * d.Cols
+			if bufs := free[n]; len(bufs) > 0 {
+				d.Buffer = bufs[len(bufs)-1]
+				free[n] = bufs[:len(bufs)-1]
+			} else {
+				d.Buffer = len(p.Buffers)
+				p.Buffers = append(p.Buffers, n)
+			}
+		}
+		// The destination is assigned first, so it never shares a buffer
+		// with an operand of the same instruction.
+		for _, s := range append([]int{in.Dst}, in.Args...) {
+			if sl := p.Slots[s]; !sl.Input && last[s] == i {
+				last[s] = -1
+				free[p.Buffers[sl.Buffer]] = append(free[p.Buffers[sl.Buffer]], sl.Buffer)
+			}
+		}
+	}
+
+	bufs := make([][]float64, len(p.Buffers))
+	for i, n := range p.Buffers {
+		bufs[i] = make([]float64, n)
+	}
+	for i := range p.Slots {
+		if s := &p.Slots[i]; !s.Input {
+			s.view.Data = bufs[s.Buffer]
+		}
+	}
+}
+
+// String implements the Stringer interface.  It lists the slots, then the
+// instructions, and then the slot holding the result.
+func (p *Program) String() string {
+	var b bytes.Buffer
+	for i, s := range p.Slots {
+		fmt.Fprintf(&b, "s%d: %dx%d ", i, s.Rows, s.Cols)
+		switch {
+		case s.ph:
+			fmt.Fprintf(&b, "placeholder %q\n", s.Name)
+		case s.Input:
+			b.WriteString("literal\n")
+		default:
+			fmt.Fprintf(&b, "buffer %d\n", s.Buffer)
+		}
+	}
+	for _, in := range p.Instrs {
+		b.WriteString(in.String())
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "return s%d", p.Result)
+	return b.String()
+}
+
+// Exec executes the program with the given bindings of its placeholders, and
+// returns a copy of the result.
+func (p *Program) Exec(bindings map[string]MatrixLiteral) (MatrixLiteral, error) {
+	s := p.Slots[p.Result]
+	m := &General{blas64.General{
+		Rows:   s.Rows,
+		Cols:   s.Cols,
+		Stride: s.Cols,
+		Data:   make([]float64, s.Rows*s.Cols),
+	}}
+	if err := p.ExecInto(m, bindings); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ExecInto executes the program with the given bindings of its placeholders,
+// and copies the result into an existing matrix literal, which must have the
+// same dimensions.
+func (p *Program) ExecInto(dst MatrixLiteral, bindings map[string]MatrixLiteral) error {
+	return p.ExecContext(context.Background(), dst, bindings)
+}
+
+// ExecContext is like ExecInto, but stops early and returns the context's
+// error if the context is done first.
+func (p *Program) ExecContext(ctx context.Context, dst MatrixLiteral, bindings map[string]MatrixLiteral) (err error) {
+	if err := checkDst(p.exp, dst); err != nil {
+		return err
+	}
+	for i := range p.Slots {
+		s := &p.Slots[i]
+		if !s.ph {
+			continue
+		}
+		ph := &Placeholder{
+			Name:  s.Name,
+			R:     s.Rows,
+			C:     s.Cols,
+			value: bindings[s.Name],
+		}
+		if err := ph.Err(); err != nil {
+			return err
+		}
+		s.view.General = ph.value.AsGeneral()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(abort)
+			if !ok {
+				panic(r)
+			}
+			err = a.err
+		}
+	}()
+	for _, in := range p.Instrs {
+		check(ctx)
+		p.exec(ctx, in)
+	}
+	copyInto(dst.AsGeneral(), p.Slots[p.Result].view.General)
+	return nil
+}
+
+// exec executes a single instruction.
+func (p *Program) exec(ctx context.Context, in Instr) {
+	d := p.Slots[in.Dst].view.General
+	arg := func(i int) blas64.General {
+		return p.Slots[in.Args[i]].view.General
+	}
+	switch in.Op {
+	case OpCopy:
+		copyInto(d, arg(0))
+	case OpGemm:
+		tA, tB := blas.NoTrans, blas.NoTrans
+		if in.TransA {
+			tA = blas.Trans
+		}
+		if in.TransB {
+			tB = blas.Trans
+		}
+		blas64.Gemm(tA, tB, in.Alpha, arg(0), arg(1), 0, d)
+	case OpAxpy:
+		x := arg(0)
+		for i := 0; i < d.Rows; i++ {
+			blas64.Axpy(d.Cols, in.Alpha,
+				blas64.Vector{Inc: 1, Data: x.Data[i*x.Stride : i*x.Stride+x.Cols]},
+				blas64.Vector{Inc: 1, Data: d.Data[i*d.Stride : i*d.Stride+d.Cols]})
+		}
+	case OpScale:
+		x := arg(0)
+		for i := 0; i < d.Rows; i++ {
+			row := d.Data[i*d.Stride : i*d.Stride+d.Cols]
+			for j, v := range x.Data[i*x.Stride : i*x.Stride+x.Cols] {
+				row[j] = in.Alpha * v
+			}
+		}
+	case OpTranspose:
+		transposeInto(ctx, d, arg(0))
+	case OpMulElem, OpDivElem:
+		x, y := arg(0), arg(1)
+		for i := 0; i < d.Rows; i++ {
+			row := d.Data[i*d.Stride : i*d.Stride+d.Cols]
+			yrow := y.Data[i*y.Stride : i*y.Stride+y.Cols]
+			for j, v := range x.Data[i*x.Stride : i*x.Stride+x.Cols] {
+				if in.Op == OpMulElem {
+					row[j] = v * yrow[j]
+				} else {
+					row[j] = v / yrow[j]
+				}
+			}
+		}
+	case OpEval:
+		if err := evalIntoCtx(ctx, in.Exp, p.Slots[in.Dst].view); err != nil {
+			panic(abort{err})
+		}
+	}
+}
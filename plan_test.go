@@ -0,0 +1,78 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+	h := &General{rnd(2, 3)}
+	r := &General{eye(2)}
+	b := &General{rnd(2, 2)}
+	P := NewPlaceholder("P", 3, 3)
+	x := NewPlaceholder("x", 3, 1)
+	s := h.Mul(P).Mul(h.T()).Add(r)
+	k := P.Mul(h.T()).Mul(s.Inv())
+	exps := []MatrixExp{
+		P.Sub(k.Mul(h).Mul(P)),
+		x.Add(k.Mul(h.Mul(x).Scale(-1))),
+		P.T().Mul(P).Scale(2).MulElem(P).DivElem(P.Add(P)),
+		(&Kron{Left: x, Right: b}).Mul(b.Mul(b)),
+		h,
+	}
+	for _, m := range exps {
+		p, err := Plan(m)
+		if err != nil {
+			t.Fatalf("Plan(%v) returned error %v", m, err)
+		}
+		for i := 0; i < 3; i++ {
+			bindings := map[string]MatrixLiteral{
+				"P": wellConditioned(3).Scale(float64(i + 1)).Eval(),
+				"x": &General{rnd(3, 1)},
+			}
+			want, err := EvalWith(m, bindings)
+			if err != nil {
+				t.Fatalf("EvalWith(%v) returned error %v", m, err)
+			}
+			got, err := p.Exec(bindings)
+			if err != nil {
+				t.Fatalf("Plan(%v).Exec returned error %v", m, err)
+			}
+			if !EqualsApprox(got, want, Tolerance{Tol: 1e-9}) {
+				t.Errorf("Plan(%v).Exec equals %v, want %v\n%v", m, got, want, p)
+			}
+		}
+	}
+}
+
+func TestPlanBuffers(t *testing.T) {
+	t.Parallel()
+	a := &General{rnd(4, 4)}
+	x := NewPlaceholder("x", 4, 4)
+	m := x.Mul(a).Mul(a).Mul(a).Add(x).T()
+	p, err := Plan(m)
+	if err != nil {
+		t.Fatalf("Plan(%v) returned error %v", m, err)
+	}
+	// Each product only needs the one before it, so two buffers can be
+	// reused for all of them.
+	if got := len(p.Buffers); got != 2 {
+		t.Errorf("Plan(%v) has %v buffers, want 2\n%v", m, got, p)
+	}
+	if s := p.String(); !strings.Contains(s, "gemm(1, s0, s1)") || !strings.Contains(s, `placeholder "x"`) {
+		t.Errorf("Plan(%v).String() equals\n%v\nwant a gemm of s0 and s1, and a placeholder", m, s)
+	}
+
+	if _, err := p.Exec(nil); err != ErrUnbound("x") {
+		t.Errorf("Plan(%v).Exec(nil) returned error %v, want %v", m, err, ErrUnbound("x"))
+	}
+	bad := &General{rnd(4, 4)}
+	if _, err := Plan(bad.Mul(&General{rnd(3, 4)})); err == nil {
+		t.Errorf("Plan of a mismatched product returned nil error")
+	}
+}
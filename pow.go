@@ -0,0 +1,237 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"github.com/gonum/blas"
+	"github.com/gonum/blas/blas64"
+	"strconv"
+)
+
+// Pow represents a square matrix raised to an integer power.  Negative powers
+// are powers of the inverse.
+type Pow struct {
+	M MatrixExp
+	K int
+}
+
+// String implements the Stringer interface.
+func (m1 *Pow) String() string {
+	return "Pow{" + m1.M.String() + ", " + strconv.Itoa(m1.K) + "}"
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Pow) Dims() (r, c int) {
+	r, c = m1.M.Dims()
+	return
+}
+
+// At returns the value at a given row, column index.  This evaluates the
+// whole power.
+func (m1 *Pow) At(r, c int) float64 {
+	return m1.Eval().At(r, c)
+}
+
+// Eval returns a matrix literal.  The power is found by repeated squaring.
+func (m1 *Pow) Eval() MatrixLiteral {
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Pow) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Pow) evalCtx(ctx context.Context) MatrixLiteral {
+	n, _ := m1.M.Dims()
+	k := m1.K
+	m := m1.M
+	if k < 0 {
+		m = &Inv{m1.M}
+		k = -k
+	}
+	base := evalCtx(ctx, m)
+	p := power(ctx, base.AsGeneral(), n, k)
+	release(m, base)
+	return &General{p}
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Pow) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Pow) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Pow) Copy() MatrixExp {
+	return &Pow{
+		M: m1.M.Copy(),
+		K: m1.K,
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Pow) Err() error {
+	if err := m1.M.Err(); err != nil {
+		return err
+	}
+	if r, c := m1.M.Dims(); r != c {
+		return ErrNotSquare{
+			R: r,
+			C: c,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *Pow) T() MatrixExp {
+	return &T{m1}
+}
+
+// Add two matrices together.
+func (m1 *Pow) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Pow) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Pow) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.  Powers of the same expression are
+// combined by adding exponents.
+func (m1 *Pow) Mul(m2 MatrixExp) MatrixExp {
+	if p, ok := m2.(*Pow); ok && p.M == m1.M {
+		return &Pow{
+			M: m1.M,
+			K: m1.K + p.K,
+		}
+	}
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Pow) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Pow) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Pow) Inv() MatrixExp {
+	return &Pow{
+		M: m1.M,
+		K: -m1.K,
+	}
+}
+
+// identity returns a newly allocated n x n identity matrix.
+func identity(n int) blas64.General {
+	g := blas64.General{
+		Rows:   n,
+		Cols:   n,
+		Stride: n,
+		Data:   getAllocator().Get(n * n),
+	}
+	for i := 0; i < n*n; i += n + 1 {
+		g.Data[i] = 1
+	}
+	return g
+}
+
+// gemm returns the product of a and b in a newly allocated matrix.
+func gemm(a, b blas64.General) blas64.General {
+	c := blas64.General{
+		Rows:   a.Rows,
+		Cols:   b.Cols,
+		Stride: b.Cols,
+		Data:   getAllocator().Get(a.Rows * b.Cols),
+	}
+	blas64.Gemm(blas.NoTrans, blas.NoTrans, 1, a, b, 0, c)
+	return c
+}
+
+// power raises the n x n matrix a to the non-negative power k by repeated
+// squaring under ctx.  The result never shares memory with a.
+func power(ctx context.Context, a blas64.General, n, k int) blas64.General {
+	if k == 0 {
+		return identity(n)
+	}
+	alloc := getAllocator()
+	var p blas64.General
+	started := false
+	for sq := a; ; {
+		check(ctx)
+		if k&1 == 1 {
+			if started {
+				q := gemm(p, sq)
+				alloc.Put(p.Data)
+				p = q
+			} else {
+				p = copyGeneral(sq)
+				started = true
+			}
+		}
+		if k >>= 1; k == 0 {
+			if !sameArray(sq.Data, a.Data) {
+				alloc.Put(sq.Data)
+			}
+			return p
+		}
+		next := gemm(sq, sq)
+		if !sameArray(sq.Data, a.Data) {
+			alloc.Put(sq.Data)
+		}
+		sq = next
+	}
+}
+
+// copyGeneral returns a compact copy of a.
+func copyGeneral(a blas64.General) blas64.General {
+	v := getAllocator().Get(a.Rows * a.Cols)
+	for i := 0; i < a.Rows; i++ {
+		copy(v[i*a.Cols:(i+1)*a.Cols], a.Data[i*a.Stride:i*a.Stride+a.Cols])
+	}
+	return blas64.General{
+		Rows:   a.Rows,
+		Cols:   a.Cols,
+		Stride: a.Cols,
+		Data:   v,
+	}
+}
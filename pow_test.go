@@ -0,0 +1,113 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"github.com/gonum/blas/blas64"
+	"math"
+	"testing"
+)
+
+// near determines if two matrices are equal to within an absolute tolerance.
+func near(m1, m2 MatrixExp, tol float64) bool {
+	r1, c1 := m1.Dims()
+	r2, c2 := m2.Dims()
+	if r1 != r2 || c1 != c2 {
+		return false
+	}
+	for i := 0; i < r1; i++ {
+		for j := 0; j < c1; j++ {
+			if math.Abs(m1.At(i, j)-m2.At(i, j)) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// wellConditioned is an invertible matrix.
+func wellConditioned(n int) *General {
+	m := &General{rnd(n, n)}
+	for i := 0; i < n; i++ {
+		m.Set(i, i, m.At(i, i)+float64(n))
+	}
+	return m
+}
+
+func TestInv(t *testing.T) {
+	t.Parallel()
+	for _, n := range []int{1, 2, 5} {
+		a := wellConditioned(n)
+		want := &General{eye(n)}
+		if got := a.Mul(a.Inv()).Eval(); !near(got, want, 1e-12) {
+			t.Errorf("%v * %v equals %v, want %v", a, a.Inv(), got, want)
+		}
+		if got := a.Inv().Inv(); got != a {
+			t.Errorf("%v.Inv().Inv() equals %v, want %v", a, got, a)
+		}
+	}
+}
+
+func TestPow(t *testing.T) {
+	t.Parallel()
+	a := wellConditioned(5)
+	for _, tt := range []struct {
+		k    int
+		want MatrixExp
+	}{
+		{k: 0, want: &General{eye(5)}},
+		{k: 1, want: a},
+		{k: 2, want: a.Mul(a)},
+		{k: 5, want: a.Mul(a).Mul(a).Mul(a).Mul(a)},
+		{k: -1, want: a.Inv()},
+		{k: -3, want: a.Inv().Mul(a.Inv()).Mul(a.Inv())},
+	} {
+		got := (&Pow{M: a, K: tt.k}).Eval()
+		if !near(got, tt.want.Eval(), 1e-9) {
+			t.Errorf("Pow{a, %d} equals %v, want %v", tt.k, got, tt.want.Eval())
+		}
+	}
+	if got := (&Pow{M: a, K: 1}).Eval(); got == MatrixLiteral(a) {
+		t.Errorf("Pow{a, 1} shares its result with a")
+	}
+}
+
+func TestExpm(t *testing.T) {
+	t.Parallel()
+	// exp of a diagonal matrix is the exp of its diagonal.
+	d := GeneralZeros(3, 3).(*General)
+	want := GeneralZeros(3, 3).(*General)
+	for i, v := range []float64{-2, 0.5, 7} {
+		d.Set(i, i, v)
+		want.Set(i, i, math.Exp(v))
+	}
+	if got := (&Expm{d}).Eval(); !near(got, want, 1e-9*math.Exp(7)) {
+		t.Errorf("Expm{%v} equals %v, want %v", d, got, want)
+	}
+
+	// exp of a nilpotent matrix is a finite series.
+	n := &General{blas64.General{Rows: 2, Cols: 2, Stride: 2, Data: []float64{0, 3, 0, 0}}}
+	want = &General{blas64.General{Rows: 2, Cols: 2, Stride: 2, Data: []float64{1, 3, 0, 1}}}
+	if got := (&Expm{n}).Eval(); !near(got, want, 1e-12) {
+		t.Errorf("Expm{%v} equals %v, want %v", n, got, want)
+	}
+
+	// exp(A) * exp(-A) = I
+	a := GeneralRand(5, 5)
+	e := &Expm{a}
+	if got := e.Mul(e.Inv()).Eval(); !near(got, &General{eye(5)}, 1e-9) {
+		t.Errorf("Expm{a} * Expm{a}.Inv() equals %v, want I", got)
+	}
+}
+
+func TestNotSquare(t *testing.T) {
+	t.Parallel()
+	a := GeneralOnes(2, 3)
+	for _, m := range []MatrixExp{a.Inv(), &Pow{M: a, K: 2}, &Expm{a}} {
+		if err := m.Err(); err != (ErrNotSquare{R: 2, C: 3}) {
+			t.Errorf("%v.Err() equals %v, want %v", m, err, ErrNotSquare{R: 2, C: 3})
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rewrite
+
+import (
+	"github.com/jonlawlor/matrixexp"
+	"reflect"
+)
+
+// CSE eliminates common subexpressions.  Subexpressions which appear more than
+// once in a matrix expression, either because the same expression is used
+// more than once or because structurally identical expressions are, are
+// replaced by a single shared matrixexp.Memo so that they are only evaluated
+// once.  Two literals are only the same if they are the same literal.
+//
+// The input expression is not modified.  Other rewriters don't look inside of
+// memos, so CSE should be the last rewrite applied to an expression.
+var CSE Rewriter = RewriterFunc(cse)
+
+// cseNode is a distinct subexpression.
+type cseNode struct {
+	exp      matrixexp.MatrixExp // the first occurrence of the subexpression
+	children []int
+	uses     int
+	leaf     bool
+	built    matrixexp.MatrixExp
+}
+
+// cseState holds the distinct subexpressions of an expression, by hash.
+type cseState struct {
+	ids   map[uint64][]int
+	nodes []*cseNode
+}
+
+func cse(m1 matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	s := &cseState{ids: make(map[uint64][]int)}
+	root := s.visit(m1)
+
+	// Count the uses of each distinct subexpression by the other distinct
+	// subexpressions, because each of those will only be evaluated once.
+	for _, n := range s.nodes {
+		for _, c := range n.children {
+			s.nodes[c].uses++
+		}
+	}
+	return s.build(root), nil
+}
+
+// visit finds the distinct subexpressions of m, and returns the id of m.
+func (s *cseState) visit(m matrixexp.MatrixExp) int {
+	if memo, ok := m.(*matrixexp.Memo); ok {
+		return s.visit(memo.M)
+	}
+	h := matrixexp.Hash(m)
+	for _, id := range s.ids[h] {
+		if matrixexp.StructEqual(m, s.nodes[id].exp) {
+			return id
+		}
+	}
+
+	n := &cseNode{exp: m}
+	rm := reflect.ValueOf(m)
+	if _, ok := m.(matrixexp.MatrixLiteral); ok || rm.Kind() != reflect.Ptr || rm.Elem().Kind() != reflect.Struct {
+		// there is no need (or no way) to rebuild the expression
+		n.leaf = true
+	} else {
+		rm = rm.Elem()
+		for i := 0; i < rm.NumField(); i++ {
+			if f := rm.Field(i); f.CanInterface() && isExpField(f) {
+				n.children = append(n.children, s.visit(f.Interface().(matrixexp.MatrixExp)))
+			}
+		}
+	}
+
+	id := len(s.nodes)
+	s.ids[h] = append(s.ids[h], id)
+	s.nodes = append(s.nodes, n)
+	return id
+}
+
+// build constructs the expression with the given id, sharing subexpressions
+// that are used more than once.
+func (s *cseState) build(id int) matrixexp.MatrixExp {
+	n := s.nodes[id]
+	if n.built != nil {
+		return n.built
+	}
+	m := n.exp
+	if !n.leaf {
+		// copy the node, and then replace its subexpressions
+		rm := reflect.ValueOf(m)
+		cp := reflect.New(rm.Elem().Type())
+		cp.Elem().Set(rm.Elem())
+		j := 0
+		for i := 0; i < cp.Elem().NumField(); i++ {
+			if f := cp.Elem().Field(i); f.CanSet() && isExpField(f) {
+				f.Set(reflect.ValueOf(s.build(n.children[j])))
+				j++
+			}
+		}
+		m = cp.Interface().(matrixexp.MatrixExp)
+		if n.uses > 1 {
+			m = matrixexp.NewMemo(m, n.uses)
+		}
+	}
+	n.built = m
+	return m
+}
+
+// isExpField determines if a struct field holds a matrix expression, which
+// can be replaced by any other matrix expression.
+func isExpField(f reflect.Value) bool {
+	return f.Kind() == reflect.Interface && f.Type().Implements(rMatrixExp) && !f.IsNil()
+}
@@ -0,0 +1,264 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rewrite
+
+import (
+	"context"
+	"fmt"
+	"github.com/jonlawlor/matrixexp"
+	"strconv"
+	"sync"
+)
+
+// Dim is a dimension of a wildcard in a template.  It is either a fixed size,
+// or a symbolic variable, which is bound to the size of the first expression
+// it matches, and must be the same size everywhere else it appears in the
+// template.
+//
+// Symbolic variables are negative, so that the dimensions of a template built
+// from wildcards are also symbolic: the Dims of AnyA.Mul(AnyB) are the rows of
+// AnyA and the columns of AnyB, and its Err reports an ErrInnerDimMismatch
+// unless the columns of AnyA and the rows of AnyB are the same variable.
+type Dim int
+
+// syms holds the symbolic variables, by name.
+var syms struct {
+	sync.Mutex
+	ids   map[string]Dim
+	names []string
+}
+
+// Sym returns the symbolic dimension variable with the given name.  Every
+// call with the same name returns the same variable.
+func Sym(name string) Dim {
+	syms.Lock()
+	defer syms.Unlock()
+	if d, ok := syms.ids[name]; ok {
+		return d
+	}
+	if syms.ids == nil {
+		syms.ids = make(map[string]Dim)
+	}
+	syms.names = append(syms.names, name)
+	d := Dim(-len(syms.names))
+	syms.ids[name] = d
+	return d
+}
+
+// Size returns a fixed dimension.  It panics if n is negative, because a
+// negative Dim is a symbolic variable.
+func Size(n int) Dim {
+	if n < 0 {
+		panic(fmt.Sprintf("negative size %d", n))
+	}
+	return Dim(n)
+}
+
+// IsSym determines if d is a symbolic variable.
+func (d Dim) IsSym() bool {
+	return d < 0
+}
+
+// String implements the Stringer interface.
+func (d Dim) String() string {
+	if !d.IsSym() {
+		return strconv.Itoa(int(d))
+	}
+	syms.Lock()
+	defer syms.Unlock()
+	return syms.names[-d-1]
+}
+
+// A ShapeMatcher is a Matcher with dimensions, which have to be consistent with
+// the dimensions of all of the other ShapeMatchers in a template for it to
+// match.
+type ShapeMatcher interface {
+	Matcher
+	Shape() (r, c Dim)
+}
+
+// DimMismatch indicates that the dimensions of an expression are not
+// consistent with the dimensions of the wildcard that it was matched with.
+type DimMismatch struct {
+	Dim  Dim
+	Want int
+	Got  int
+}
+
+// Error implements the error interface.
+func (e *DimMismatch) Error() string {
+	if e.Dim.IsSym() {
+		return fmt.Sprintf("dimension %v is bound to %d, got %d", e.Dim, e.Want, e.Got)
+	}
+	return fmt.Sprintf("dimension mismatch: expected %d, got %d", e.Want, e.Got)
+}
+
+// unify binds the dimension d to n, or returns an error if it is already bound
+// to a different size.
+func (m *match) unify(d Dim, n int) error {
+	want := int(d)
+	if d.IsSym() {
+		if w, ok := m.dims[d]; ok {
+			want = w
+		} else {
+			m.dims[d] = n
+			return nil
+		}
+	}
+	if want != n {
+		return &DimMismatch{
+			Dim:  d,
+			Want: want,
+			Got:  n,
+		}
+	}
+	return nil
+}
+
+// NewShapedExp returns a wildcard which matches any matrix expression with
+// the given dimensions.  For example, with n := Sym("n"), NewShapedExp(n, n)
+// only matches square matrices, NewShapedExp(n, Size(1)) only matches column
+// vectors, and two wildcards which are both NewShapedExp(Sym("r"), Sym("c"))
+// only match expressions with the same shape.
+func NewShapedExp(r, c Dim) *ShapedExp {
+	return &ShapedExp{
+		R: r,
+		C: c,
+	}
+}
+
+// ShapedExp is a wildcard like AnyExp, which only matches expressions with the
+// right dimensions.  It is intended for use with the Template rewriter, and
+// will cause a runtime panic if it is ever used for calculations.
+type ShapedExp struct {
+	R, C Dim
+}
+
+// String implements the Stringer interface.
+func (m1 *ShapedExp) String() string {
+	return "Any(" + m1.R.String() + ", " + m1.C.String() + ")"
+}
+
+// Dims returns the matrix dimensions, which are negative if they are
+// symbolic.
+func (m1 *ShapedExp) Dims() (r, c int) {
+	return int(m1.R), int(m1.C)
+}
+
+// At returns the value at a given row, column index.
+func (m1 *ShapedExp) At(r, c int) float64 {
+	panic("cannot evaluate a ShapedExp")
+}
+
+// Eval returns a matrix literal.
+func (m1 *ShapedExp) Eval() matrixexp.MatrixLiteral {
+	panic("cannot evaluate a ShapedExp")
+}
+
+// EvalContext evaluates the matrix expression under a context.
+func (m1 *ShapedExp) EvalContext(ctx context.Context) (matrixexp.MatrixLiteral, error) {
+	panic("cannot evaluate a ShapedExp")
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal.
+func (m1 *ShapedExp) EvalInto(dst matrixexp.MatrixLiteral) error {
+	panic("cannot evaluate a ShapedExp")
+}
+
+// Copy normally creates a (deep) copy of the Matrix Expression.  However, to
+// aid in rewriting expressions, Copy() of matrix expression wildcards is a nop.
+func (m1 *ShapedExp) Copy() matrixexp.MatrixExp {
+	return m1
+}
+
+// Err returns the first error encountered while constructing the matrix
+// expression.  A wildcard has no errors of its own, so the Err of a template
+// reports whether its symbolic dimensions are consistent.
+func (m1 *ShapedExp) Err() error {
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *ShapedExp) T() matrixexp.MatrixExp {
+	return &matrixexp.T{M: m1}
+}
+
+// Add two matrices together.
+func (m1 *ShapedExp) Add(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *ShapedExp) Sub(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *ShapedExp) Scale(c float64) matrixexp.MatrixExp {
+	return &matrixexp.Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *ShapedExp) Mul(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *ShapedExp) MulElem(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *ShapedExp) DivElem(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *ShapedExp) Inv() matrixexp.MatrixExp {
+	return &matrixexp.Inv{M: m1}
+}
+
+// Match determines if a matrix expression wildcard matches another matrix
+// expression.  Only fixed dimensions are checked here; symbolic ones are
+// unified by the template.
+func (m1 *ShapedExp) Match(m2 matrixexp.MatrixExp) error {
+	r, c := m2.Dims()
+	for _, d := range []struct {
+		want Dim
+		got  int
+	}{{m1.R, r}, {m1.C, c}} {
+		if !d.want.IsSym() && int(d.want) != d.got {
+			return &DimMismatch{
+				Dim:  d.want,
+				Want: int(d.want),
+				Got:  d.got,
+			}
+		}
+	}
+	return nil
+}
+
+// Shape returns the dimensions of the wildcard.
+func (m1 *ShapedExp) Shape() (r, c Dim) {
+	return m1.R, m1.C
+}
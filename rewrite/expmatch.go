@@ -5,6 +5,7 @@
 package rewrite
 
 import (
+	"context"
 	"github.com/jonlawlor/matrixexp"
 )
 
@@ -37,6 +38,16 @@ func (m1 *AnyExp) Eval() matrixexp.MatrixLiteral {
 	panic("cannot evaluate an AnyExpr")
 }
 
+// EvalContext evaluates the matrix expression under a context.
+func (m1 *AnyExp) EvalContext(ctx context.Context) (matrixexp.MatrixLiteral, error) {
+	panic("cannot evaluate an AnyExpr")
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal.
+func (m1 *AnyExp) EvalInto(dst matrixexp.MatrixLiteral) error {
+	panic("cannot evaluate an AnyExpr")
+}
+
 // Copy normally creates a (deep) copy of the Matrix Expression.  However, to
 // aid in rewriting expressions, Copy() of matrix expression wildcards is a nop.
 func (m1 *AnyExp) Copy() matrixexp.MatrixExp {
@@ -102,6 +113,11 @@ func (m1 *AnyExp) DivElem(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
 	}
 }
 
+// Inv inverts a matrix.
+func (m1 *AnyExp) Inv() matrixexp.MatrixExp {
+	return &matrixexp.Inv{M: m1}
+}
+
 // Match determines if a matrix expression wildcard matches another matrix
 // expression.
 func (m1 *AnyExp) Match(m2 matrixexp.MatrixExp) error {
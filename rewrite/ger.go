@@ -0,0 +1,99 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rewrite
+
+import (
+	"github.com/jonlawlor/matrixexp"
+)
+
+// These rules are written by hand rather than as Templates, because each of
+// them covers several forms of the same expression.
+
+// OuterProduct rewrites u.Mul(v.T()), where u and v are column vectors, into
+// an Outer product so that it can be evaluated with blas64.Ger.
+var OuterProduct Rewriter = RewriterFunc(outerProduct)
+
+// RankOneUpdate rewrites the sum (or difference) of a matrix and a possibly
+// scaled outer product, such as A.Add(u.Mul(v.T()).Scale(alpha)), into a Ger
+// expression so that the outer product is never formed.  When the outer
+// product is on the left of a difference, the matrix is negated.
+var RankOneUpdate Rewriter = RewriterFunc(rankOneUpdate)
+
+func outerProduct(m1 matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	if m, ok := m1.(*matrixexp.Mul); ok {
+		if u, v, alpha, ok := outerParts(m); ok && alpha == 1 {
+			return &matrixexp.Outer{
+				U: u,
+				V: v,
+			}, nil
+		}
+	}
+	return nil, &RuleMismatch{"OuterProduct", m1}
+}
+
+func rankOneUpdate(m1 matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	switch m := m1.(type) {
+	case *matrixexp.Add:
+		if u, v, alpha, ok := outerParts(m.Right); ok {
+			return &matrixexp.Ger{
+				A:     m.Left,
+				Alpha: alpha,
+				U:     u,
+				V:     v,
+			}, nil
+		}
+		if u, v, alpha, ok := outerParts(m.Left); ok {
+			return &matrixexp.Ger{
+				A:     m.Right,
+				Alpha: alpha,
+				U:     u,
+				V:     v,
+			}, nil
+		}
+	case *matrixexp.Sub:
+		if u, v, alpha, ok := outerParts(m.Right); ok {
+			return &matrixexp.Ger{
+				A:     m.Left,
+				Alpha: -alpha,
+				U:     u,
+				V:     v,
+			}, nil
+		}
+		if u, v, alpha, ok := outerParts(m.Left); ok {
+			return &matrixexp.Ger{
+				A:     m.Right.Scale(-1),
+				Alpha: alpha,
+				U:     u,
+				V:     v,
+			}, nil
+		}
+	}
+	return nil, &RuleMismatch{"RankOneUpdate", m1}
+}
+
+// outerParts determines if a matrix expression is a scaled outer product of
+// two column vectors, and if so returns the vectors and the scale.
+func outerParts(m1 matrixexp.MatrixExp) (u, v matrixexp.MatrixExp, alpha float64, ok bool) {
+	switch m := m1.(type) {
+	case *matrixexp.Outer:
+		return m.U, m.V, 1, true
+	case *matrixexp.Mul:
+		t, isT := m.Right.(*matrixexp.T)
+		if !isT || !isColVector(m.Left) || !isColVector(t.M) {
+			return nil, nil, 0, false
+		}
+		return m.Left, t.M, 1, true
+	case *matrixexp.Scale:
+		u, v, alpha, ok = outerParts(m.M)
+		return u, v, alpha * m.C, ok
+	}
+	return nil, nil, 0, false
+}
+
+// isColVector determines if a matrix expression is a column vector.
+func isColVector(m1 matrixexp.MatrixExp) bool {
+	_, c := m1.Dims()
+	return c == 1
+}
@@ -0,0 +1,198 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rewrite
+
+import (
+	"context"
+	"fmt"
+	"github.com/jonlawlor/matrixexp"
+	"reflect"
+)
+
+// Where returns a wildcard which matches the matrix expressions that satisfy
+// a predicate, which returns an error explaining why an expression doesn't
+// satisfy it.  The name is used to print the wildcard.
+func Where(name string, pred func(matrixexp.MatrixExp) error) *PredExp {
+	return &PredExp{
+		name: name,
+		pred: pred,
+	}
+}
+
+// AnyLiteral returns a wildcard which only matches matrix literals.
+func AnyLiteral() *PredExp {
+	return Where("AnyLiteral", func(m matrixexp.MatrixExp) error {
+		if _, ok := m.(matrixexp.MatrixLiteral); !ok {
+			return &WildcardMismatch{"AnyLiteral", m}
+		}
+		return nil
+	})
+}
+
+// AnyGeneral returns a wildcard which only matches *matrixexp.General.
+func AnyGeneral() *PredExp {
+	return AnyOfType(&matrixexp.General{})
+}
+
+// AnySquare returns a wildcard which only matches square matrices.
+func AnySquare() *PredExp {
+	return Where("AnySquare", func(m matrixexp.MatrixExp) error {
+		if r, c := m.Dims(); r != c {
+			return &WildcardMismatch{"AnySquare", m}
+		}
+		return nil
+	})
+}
+
+// AnyVector returns a wildcard which only matches column vectors.
+func AnyVector() *PredExp {
+	return Where("AnyVector", func(m matrixexp.MatrixExp) error {
+		if _, c := m.Dims(); c != 1 {
+			return &WildcardMismatch{"AnyVector", m}
+		}
+		return nil
+	})
+}
+
+// AnyOfType returns a wildcard which only matches matrix expressions with the
+// same type as an example, such as (*matrixexp.Kron)(nil).  It panics if the
+// example is a nil interface, which has no type.
+func AnyOfType(example matrixexp.MatrixExp) *PredExp {
+	t := reflect.TypeOf(example)
+	if t == nil {
+		panic("AnyOfType needs a typed example, such as (*matrixexp.Kron)(nil)")
+	}
+	return Where("AnyOfType("+t.String()+")", func(m matrixexp.MatrixExp) error {
+		if reflect.TypeOf(m) != t {
+			return &ExpMismatch{example, m}
+		}
+		return nil
+	})
+}
+
+// WildcardMismatch indicates that a matrix expression does not satisfy the
+// predicate of a wildcard.
+type WildcardMismatch struct {
+	wildcard string
+	got      matrixexp.MatrixExp
+}
+
+// Error implements the error interface.
+func (e *WildcardMismatch) Error() string {
+	return fmt.Sprintf("wildcard %s does not match %v", e.wildcard, e.got)
+}
+
+// PredExp is a wildcard which only matches matrix expressions that satisfy a
+// predicate.  It is intended for use with the Template rewriter, and will
+// cause a runtime panic if it is ever used for calculations.
+type PredExp struct {
+	name string
+	pred func(matrixexp.MatrixExp) error
+}
+
+// String implements the Stringer interface.
+func (m1 *PredExp) String() string {
+	return m1.name
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *PredExp) Dims() (r, c int) {
+	return 0, 0
+}
+
+// At returns the value at a given row, column index.
+func (m1 *PredExp) At(r, c int) float64 {
+	panic("cannot evaluate a PredExp")
+}
+
+// Eval returns a matrix literal.
+func (m1 *PredExp) Eval() matrixexp.MatrixLiteral {
+	panic("cannot evaluate a PredExp")
+}
+
+// EvalContext evaluates the matrix expression under a context.
+func (m1 *PredExp) EvalContext(ctx context.Context) (matrixexp.MatrixLiteral, error) {
+	panic("cannot evaluate a PredExp")
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal.
+func (m1 *PredExp) EvalInto(dst matrixexp.MatrixLiteral) error {
+	panic("cannot evaluate a PredExp")
+}
+
+// Copy normally creates a (deep) copy of the Matrix Expression.  However, to
+// aid in rewriting expressions, Copy() of matrix expression wildcards is a nop.
+func (m1 *PredExp) Copy() matrixexp.MatrixExp {
+	return m1
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *PredExp) Err() error {
+	panic("cannot evaluate a PredExp")
+}
+
+// T transposes a matrix.
+func (m1 *PredExp) T() matrixexp.MatrixExp {
+	return &matrixexp.T{M: m1}
+}
+
+// Add two matrices together.
+func (m1 *PredExp) Add(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *PredExp) Sub(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *PredExp) Scale(c float64) matrixexp.MatrixExp {
+	return &matrixexp.Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *PredExp) Mul(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *PredExp) MulElem(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *PredExp) DivElem(m2 matrixexp.MatrixExp) matrixexp.MatrixExp {
+	return &matrixexp.DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *PredExp) Inv() matrixexp.MatrixExp {
+	return &matrixexp.Inv{M: m1}
+}
+
+// Match determines if a matrix expression wildcard matches another matrix
+// expression.
+func (m1 *PredExp) Match(m2 matrixexp.MatrixExp) error {
+	return m1.pred(m2)
+}
@@ -7,6 +7,7 @@ package rewrite
 import (
 	"fmt"
 	"github.com/jonlawlor/matrixexp"
+	"math"
 	"reflect"
 )
 
@@ -34,6 +35,15 @@ type Rewriter interface {
 	Rewrite(matrixexp.MatrixExp) (matrixexp.MatrixExp, error)
 }
 
+// RewriterFunc is an adapter which allows an ordinary function to be used as a
+// Rewriter.
+type RewriterFunc func(matrixexp.MatrixExp) (matrixexp.MatrixExp, error)
+
+// Rewrite calls f(m1).
+func (f RewriterFunc) Rewrite(m1 matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	return f(m1)
+}
+
 // A Matcher can determine if an expression wildcard matches another matrix
 // expression.  If the expression does not match the wildcard then it returns
 // an error explaining why.
@@ -62,28 +72,54 @@ func (r *template) Rewrite(m1 matrixexp.MatrixExp) (matrixexp.MatrixExp, error)
 	// TODO(jonlawlor): implement some kind of reflection cache.
 
 	// Determine if the matrix expression matches the rewrite rule.
-	matMap := make(map[matrixexp.MatrixExp]matrixexp.MatrixExp)
-	if err := matches(m1, r.from, matMap); err != nil {
+	m := newMatch()
+	if err := matches(m1, r.from, m); err != nil {
 		return nil, err
 	}
 
 	// Construct a new matrix expression with the mapping from the rewrite rule.
-	return construct(r.to.Copy(), matMap)
+	return construct(r.to, m)
+}
+
+// match holds what the wildcards of a template have been bound to while it is
+// being matched.
+type match struct {
+	exps    map[matrixexp.MatrixExp]matrixexp.MatrixExp
+	dims    map[Dim]int
+	scalars map[uint64]float64
+}
+
+func newMatch() *match {
+	return &match{
+		exps:    make(map[matrixexp.MatrixExp]matrixexp.MatrixExp),
+		dims:    make(map[Dim]int),
+		scalars: make(map[uint64]float64),
+	}
 }
 
 // matches returns true if the matrix expression matches the template.  It also
-// modifies the map to contain the mapping between the input matrix expression
-// and the template output.
-func matches(m1, from matrixexp.MatrixExp, matMap map[matrixexp.MatrixExp]matrixexp.MatrixExp) error {
+// modifies the match to contain the mapping between the input matrix
+// expression and the template output.
+func matches(m1, from matrixexp.MatrixExp, m *match) error {
 	if w, ok := from.(Matcher); ok {
 		// from is a wildcard, so it can be directly compared
 		if err := w.Match(m1); err != nil {
 			return err
 		}
+		if s, ok := w.(ShapeMatcher); ok {
+			r, c := s.Shape()
+			mr, mc := m1.Dims()
+			if err := m.unify(r, mr); err != nil {
+				return err
+			}
+			if err := m.unify(c, mc); err != nil {
+				return err
+			}
+		}
 		// Determine if we have seen the expression before.
-		if to, seen := matMap[from]; !seen {
-			matMap[from] = m1
-		} else if seen && m1 != to {
+		if to, seen := m.exps[from]; !seen {
+			m.exps[from] = m1
+		} else if seen && !matrixexp.StructEqual(m1, to) {
 			return &NewExpMismatch{m1, to}
 		}
 		// I'm not sure if a wlldcard should have subexpressions.  For now assume
@@ -100,40 +136,89 @@ func matches(m1, from matrixexp.MatrixExp, matMap map[matrixexp.MatrixExp]matrix
 	rm1 = follow(rm1)
 	rfrom = follow(rfrom)
 	for i := 0; i < rfrom.NumField(); i++ {
-		// if rfrom is a matrix expression, call matches on it as well
-		if rf := rfrom.Field(i); rf.Type().Implements(rMatrixExp) {
-			if err := matches(rf.Interface().(matrixexp.MatrixExp), rm1.Field(i).Interface().(matrixexp.MatrixExp), matMap); err != nil {
+		rf := rfrom.Field(i)
+		if !rf.CanInterface() {
+			continue
+		}
+		switch {
+		case rf.Type().Implements(rMatrixExp):
+			// if rfrom is a matrix expression, call matches on it as well
+			if err := matches(rm1.Field(i).Interface().(matrixexp.MatrixExp), rf.Interface().(matrixexp.MatrixExp), m); err != nil {
+				return err
+			}
+		case rf.Kind() == reflect.Float64:
+			// scalars may be scalar wildcards
+			if err := m.matchScalar(rm1.Field(i).Float(), rf.Float()); err != nil {
 				return err
 			}
+		default:
+			// anything else has to be equal
+			if !reflect.DeepEqual(rm1.Field(i).Interface(), rf.Interface()) {
+				return &FieldMismatch{rfrom.Type().Field(i).Name, rf.Interface(), rm1.Field(i).Interface()}
+			}
 		}
 	}
 	return nil
 }
 
-// construct takes an example matrix expression and a map from example elements
-// to realized elements (populated by the matches function) and creates a new
-// matrix expression with the same form as to but with the elements defined
-// in the matMap.
-func construct(to matrixexp.MatrixExp, matMap map[matrixexp.MatrixExp]matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
-	if r, seen := matMap[to]; seen {
+// construct takes an example matrix expression and a match from example
+// elements to realized elements (populated by the matches function) and
+// creates a new matrix expression with the same form as to but with the
+// elements defined in the match, and its scalar wildcards and expressions
+// replaced by their values.  The nodes of to are never modified, so that a
+// template can be applied any number of times: any node with a field that
+// changes is copied, and the rest are shared with the template.
+func construct(to matrixexp.MatrixExp, m *match) (matrixexp.MatrixExp, error) {
+	if r, seen := m.exps[to]; seen {
 		// we have a wildcard match / leaf
 		return r, nil
 	}
 
-	// Walk subexpressions.
 	rto := reflect.ValueOf(to)
-	rto = follow(rto)
+	if rto.Kind() != reflect.Ptr || rto.Elem().Kind() != reflect.Struct {
+		return to, nil
+	}
+	rto = rto.Elem()
+	var cp reflect.Value
+	set := func(i int, v reflect.Value) {
+		if !cp.IsValid() {
+			cp = reflect.New(rto.Type())
+			cp.Elem().Set(rto)
+		}
+		cp.Elem().Field(i).Set(v)
+	}
+
+	// Walk subexpressions.
 	for i := 0; i < rto.NumField(); i++ {
-		// if rto is a matrix expression, call construct on it as well
-		if rf := rto.Field(i); rf.Type().Implements(rMatrixExp) {
-			exp, err := construct(rf.Interface().(matrixexp.MatrixExp), matMap)
+		rf := rto.Field(i)
+		if !rf.CanInterface() {
+			continue
+		}
+		switch {
+		case rf.Type().Implements(rMatrixExp):
+			// if rto is a matrix expression, call construct on it as well
+			old := rf.Interface().(matrixexp.MatrixExp)
+			exp, err := construct(old, m)
 			if err != nil {
 				return exp, err
 			}
-			rf.Set(reflect.ValueOf(exp))
+			if exp != old {
+				set(i, reflect.ValueOf(exp))
+			}
+		case rf.Kind() == reflect.Float64:
+			v, err := m.evalScalar(rf.Float())
+			if err != nil {
+				return nil, err
+			}
+			if math.Float64bits(v) != math.Float64bits(rf.Float()) {
+				set(i, reflect.ValueOf(v).Convert(rf.Type()))
+			}
 		}
 	}
-	return to, nil
+	if !cp.IsValid() {
+		return to, nil
+	}
+	return cp.Interface().(matrixexp.MatrixExp), nil
 }
 
 // Follow pointers.
@@ -168,3 +253,28 @@ type NewExpMismatch struct {
 func (e *NewExpMismatch) Error() string {
 	return fmt.Sprintf("expected previously seen expression %v, got new %v", e.expected, e.got)
 }
+
+// FieldMismatch indicates that a field of an expression which is not a matrix
+// expression or a scalar is not equal to the same field of a template.
+type FieldMismatch struct {
+	field    string
+	expected interface{}
+	got      interface{}
+}
+
+// Error implements the error interface.
+func (e *FieldMismatch) Error() string {
+	return fmt.Sprintf("field %s mismatch: expected %v, got %v", e.field, e.expected, e.got)
+}
+
+// RuleMismatch indicates that a rewrite rule does not apply to a matrix
+// expression.
+type RuleMismatch struct {
+	rule string
+	got  matrixexp.MatrixExp
+}
+
+// Error implements the error interface.
+func (e *RuleMismatch) Error() string {
+	return "rule " + e.rule + " does not apply to " + e.got.String()
+}
@@ -7,6 +7,7 @@ package rewrite
 import (
 	"github.com/gonum/blas/blas64"
 	"github.com/jonlawlor/matrixexp"
+	"math"
 	"math/rand"
 	"testing"
 )
@@ -75,3 +76,395 @@ func TestRewrite(t *testing.T) {
 		t.Errorf("Equals(%v,%v) equals %v, want %v", ExFrom, ExTo, v, true)
 	}
 }
+
+func TestRankOneUpdate(t *testing.T) {
+	u := GeneralRand(10, 1)
+	v := GeneralOnes(4, 1)
+	a := GeneralRand(10, 4)
+	for _, tt := range []struct {
+		r    Rewriter
+		from matrixexp.MatrixExp
+		ok   bool
+	}{
+		{r: OuterProduct, from: u.Mul(v.T()), ok: true},
+		{r: OuterProduct, from: a.Mul(a.T()), ok: false},
+		{r: RankOneUpdate, from: a.Add(u.Mul(v.T())), ok: true},
+		{r: RankOneUpdate, from: u.Mul(v.T()).Add(a), ok: true},
+		{r: RankOneUpdate, from: a.Add(u.Mul(v.T()).Scale(3)), ok: true},
+		{r: RankOneUpdate, from: a.Sub(u.Mul(v.T()).Scale(3)), ok: true},
+		{r: RankOneUpdate, from: u.Mul(v.T()).Scale(3).Sub(a), ok: true},
+		{r: RankOneUpdate, from: &matrixexp.Sub{Left: &matrixexp.Outer{U: u, V: v}, Right: a}, ok: true},
+		{r: RankOneUpdate, from: a.Add(&matrixexp.Outer{U: u, V: v}), ok: true},
+		{r: RankOneUpdate, from: a.Add(a), ok: false},
+	} {
+		to, err := tt.r.Rewrite(tt.from)
+		if (err == nil) != tt.ok {
+			t.Errorf("Rewrite(%v) returned error %v, want success %v", tt.from, err, tt.ok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		switch to.(type) {
+		case *matrixexp.Outer, *matrixexp.Ger:
+		default:
+			t.Errorf("Rewrite(%v) equals %v, want an Outer or Ger", tt.from, to)
+		}
+		want := tt.from.Eval().AsVector()
+		for i, v := range to.Eval().AsVector() {
+			if math.Abs(v-want[i]) > 1e-12 {
+				t.Errorf("Rewrite(%v) equals %v, want %v", tt.from, to.Eval(), tt.from.Eval())
+				break
+			}
+		}
+	}
+}
+
+func TestCSE(t *testing.T) {
+	a := GeneralRand(4, 4)
+	b := GeneralRand(4, 4)
+	c := GeneralRand(4, 4)
+
+	from := a.Mul(b).Add(a.Mul(b).T())
+	to, err := CSE.Rewrite(from)
+	if err != nil {
+		t.Fatalf("CSE.Rewrite(%v) returned error %v", from, err)
+	}
+	add, ok := to.(*matrixexp.Add)
+	if !ok {
+		t.Fatalf("CSE.Rewrite(%v) equals %v, want an Add", from, to)
+	}
+	memo, ok := add.Left.(*matrixexp.Memo)
+	if !ok {
+		t.Fatalf("CSE.Rewrite(%v) equals %v, want a Memo on the left", from, to)
+	}
+	if tr, ok := add.Right.(*matrixexp.T); !ok || tr.M != memo {
+		t.Errorf("CSE.Rewrite(%v) equals %v, want the right to use the same Memo", from, to)
+	}
+
+	// The memo is reevaluated each time the expression is.
+	for i := 0; i < 2; i++ {
+		if v := matrixexp.Equals(from, to.Eval()); v != true {
+			t.Errorf("Equals(%v, %v) equals %v, want %v", from, to.Eval(), v, true)
+		}
+		a.(*matrixexp.General).Set(0, 0, float64(i+2))
+	}
+
+	// Shared subexpressions of a shared subexpression are only evaluated once
+	// by it, so they aren't shared.
+	x := a.Mul(b).Add(c)
+	from = x.MulElem(a.Mul(b).Add(c))
+	to, err = CSE.Rewrite(from)
+	if err != nil {
+		t.Fatalf("CSE.Rewrite(%v) returned error %v", from, err)
+	}
+	if memo, ok := to.(*matrixexp.MulElem).Left.(*matrixexp.Memo); !ok {
+		t.Errorf("CSE.Rewrite(%v) equals %v, want a Memo on the left", from, to)
+	} else if _, ok := memo.M.(*matrixexp.Add).Left.(*matrixexp.Memo); ok {
+		t.Errorf("CSE.Rewrite(%v) equals %v, want no Memo of %v", from, to, a.Mul(b))
+	}
+	for i := 0; i < 2; i++ {
+		if v := matrixexp.Equals(from, to.Eval()); v != true {
+			t.Errorf("Equals(%v, %v) equals %v, want %v", from, to.Eval(), v, true)
+		}
+		c.(*matrixexp.General).Set(1, 1, float64(i+2))
+	}
+
+	// Literals with the same values are not the same subexpression.
+	d := GeneralOnes(4, 4)
+	e := GeneralOnes(4, 4)
+	from = d.Add(e)
+	if to, _ := CSE.Rewrite(from); to.(*matrixexp.Add).Left != d || to.(*matrixexp.Add).Right != e {
+		t.Errorf("CSE.Rewrite(%v) equals %v, want %v", from, to, from)
+	}
+}
+
+func TestRewriteRepeated(t *testing.T) {
+	// A repeated wildcard matches structurally equal expressions.
+	AnyA := new(AnyExp)
+	Gram := Template(AnyA.Mul(AnyA.T()), AnyA.Mul(AnyA.T()).T())
+	x := GeneralRand(3, 3)
+	y := GeneralRand(3, 3)
+	if _, err := Gram.Rewrite(x.Add(y).Mul(x.Add(y).T())); err != nil {
+		t.Errorf("non-nil error encountered during rewrite: %v", err)
+	}
+	if _, err := Gram.Rewrite(x.Add(y).Mul(y.Add(x).T())); err == nil {
+		t.Errorf("nil error encountered during rewrite of mismatched expressions")
+	}
+}
+
+func TestCompilePlaceholder(t *testing.T) {
+	// Compile once, then evaluate with different inputs.
+	a := GeneralRand(4, 4)
+	x := matrixexp.NewPlaceholder("x", 4, 4)
+	from := a.Mul(x).Add(a.Mul(x).T())
+	to, err := CSE.Rewrite(from)
+	if err != nil {
+		t.Fatalf("CSE.Rewrite(%v) returned error %v", from, err)
+	}
+	for i := 0; i < 3; i++ {
+		b := &matrixexp.General{eye(4)}
+		b.Set(0, 1, float64(i))
+		bindings := map[string]matrixexp.MatrixLiteral{"x": b}
+		got, err := matrixexp.EvalWith(to, bindings)
+		if err != nil {
+			t.Fatalf("EvalWith(%v) returned error %v", to, err)
+		}
+		want := matrixexp.Bind(from, bindings)
+		if v := matrixexp.Equals(got, want); v != true {
+			t.Errorf("Equals(%v, %v) equals %v, want %v", got, want, v, true)
+		}
+	}
+}
+
+func TestShapedExp(t *testing.T) {
+	n, k, m := Sym("n"), Sym("k"), Sym("m")
+	A := NewShapedExp(n, k)
+	B := NewShapedExp(k, m)
+
+	// The dimensions of a template are symbolic, and checked by Err.
+	if r, c := A.Mul(B).Dims(); r != int(n) || c != int(m) {
+		t.Errorf("%v.Dims() equals %v, %v, want %v, %v", A.Mul(B), Dim(r), Dim(c), n, m)
+	}
+	if err := A.Mul(B).Err(); err != nil {
+		t.Errorf("%v.Err() equals %v, want nil", A.Mul(B), err)
+	}
+	if err := A.Mul(A).Err(); err == nil {
+		t.Errorf("%v.Err() equals nil, want an error", A.Mul(A))
+	}
+
+	S := NewShapedExp(n, n)
+	u := NewShapedExp(n, Size(1))
+	X := NewShapedExp(Sym("r"), Sym("c"))
+	Y := NewShapedExp(Sym("r"), Sym("c"))
+	a := GeneralRand(3, 4)
+	b := GeneralRand(4, 2)
+	s := GeneralRand(3, 3)
+	v := GeneralRand(3, 1)
+	for _, tt := range []struct {
+		r    Rewriter
+		from matrixexp.MatrixExp
+		ok   bool
+	}{
+		{r: Template(A.Mul(B).T(), B.T().Mul(A.T())), from: a.Mul(b).T(), ok: true},
+		{r: Template(A.Mul(B).T(), B.T().Mul(A.T())), from: a.Mul(b.Mul(b.T())).T(), ok: true},
+		{r: Template(S.Inv().Inv(), S), from: s.Inv().Inv(), ok: true},
+		{r: Template(S.Inv().Inv(), S), from: a.Inv().Inv(), ok: false},
+		{r: Template(u.T().Mul(u), u.T().Mul(u)), from: v.T().Mul(v), ok: true},
+		{r: Template(u.T().Mul(u), u.T().Mul(u)), from: s.T().Mul(s), ok: false},
+		{r: Template(X.Add(Y).T(), X.T().Add(Y.T())), from: a.Add(a).T(), ok: true},
+		{r: Template(X.Mul(Y), Y.Mul(X)), from: s.Mul(s), ok: true},
+		{r: Template(X.Mul(Y), Y.Mul(X)), from: a.Mul(b), ok: false},
+	} {
+		to, err := tt.r.Rewrite(tt.from)
+		if (err == nil) != tt.ok {
+			t.Errorf("Rewrite(%v) returned error %v, want success %v", tt.from, err, tt.ok)
+			continue
+		}
+		if err == nil {
+			if r, c := to.Dims(); r < 0 || c < 0 {
+				t.Errorf("Rewrite(%v) has dimensions %v, %v", tt.from, r, c)
+			}
+		}
+	}
+
+	// A negative size would be a symbolic variable.
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Size(-1) did not panic")
+			}
+		}()
+		Size(-1)
+	}()
+}
+
+func TestPredExp(t *testing.T) {
+	a := GeneralRand(3, 4)
+	s := GeneralRand(3, 3)
+	v := GeneralRand(3, 1)
+	for _, tt := range []struct {
+		w    Matcher
+		m    matrixexp.MatrixExp
+		want bool
+	}{
+		{AnyLiteral(), a, true},
+		{AnyLiteral(), a.T(), false},
+		{AnyLiteral(), matrixexp.NewFuture(a), true},
+		{AnyGeneral(), a, true},
+		{AnyGeneral(), matrixexp.NewFuture(a), false},
+		{AnySquare(), s.Mul(s), true},
+		{AnySquare(), a, false},
+		{AnyVector(), v, true},
+		{AnyVector(), a, false},
+		{AnyOfType((*matrixexp.Mul)(nil)), s.Mul(s), true},
+		{AnyOfType((*matrixexp.Mul)(nil)), s.Add(s), false},
+		{Where("Small", func(m matrixexp.MatrixExp) error {
+			if r, c := m.Dims(); r*c > 9 {
+				return &WildcardMismatch{"Small", m}
+			}
+			return nil
+		}), s, true},
+	} {
+		if err := tt.w.Match(tt.m); (err == nil) != tt.want {
+			t.Errorf("%v.Match(%v) equals %v, want success %v", tt.w, tt.m, err, tt.want)
+		}
+	}
+
+	// Fold the transpose into the product only when B is a literal.
+	A := new(AnyExp)
+	B := AnyLiteral()
+	r := Template(A.Mul(B).T(), B.T().Mul(A.T()))
+	if _, err := r.Rewrite(s.Mul(a).T()); err != nil {
+		t.Errorf("Rewrite(%v) returned error %v", s.Mul(a).T(), err)
+	}
+	if _, err := r.Rewrite(s.Mul(a.Scale(2)).T()); err == nil {
+		t.Errorf("Rewrite(%v) returned nil error", s.Mul(a.Scale(2)).T())
+	}
+
+	// A nil interface has no type to match.
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("AnyOfType(nil) did not panic")
+			}
+		}()
+		AnyOfType(nil)
+	}()
+}
+
+func TestScalarWildcard(t *testing.T) {
+	X := new(AnyExp)
+	a, b := Scalar("a"), Scalar("b")
+	x := GeneralRand(3, 3)
+	y := GeneralOnes(3, 3)
+	for _, tt := range []struct {
+		r    Rewriter
+		from matrixexp.MatrixExp
+		want matrixexp.MatrixExp
+	}{
+		{Template(&matrixexp.Scale{C: b, M: X.Scale(a)}, X.Scale(ScalarMul(a, b))), &matrixexp.Scale{C: 3, M: x.Scale(2)}, x.Scale(6)},
+		// Scale of a Scale keeps scalar wildcards on their own nodes.
+		{Template(X.Scale(a).Scale(b), X.Scale(ScalarMul(a, b))), &matrixexp.Scale{C: 3, M: x.Scale(2)}, x.Scale(6)},
+		{Template(X.Scale(a).Scale(2), X.Scale(a)), x.Scale(6), nil},
+		{Template(X.Scale(a).Scale(2), X.Scale(a)), &matrixexp.Scale{C: 2, M: x.Scale(3)}, x.Scale(3)},
+		{Template(X.Scale(a).Add(X.Scale(b)), X.Scale(ScalarAdd(a, b))), x.Scale(2).Add(x.Scale(5)), x.Scale(7)},
+		{Template(X.Scale(a).Sub(X.Scale(a)), X.Scale(0)), x.Scale(2).Sub(x.Scale(2)), x.Scale(0)},
+		{Template(X.Scale(2), X.Add(X)), x.Scale(2), x.Add(x)},
+		{Template(X.Scale(2), X.Add(X)), x.Scale(7), nil},
+		{Template(X.Scale(a).Sub(X.Scale(a)), X.Scale(0)), x.Scale(2).Sub(x.Scale(3)), nil},
+		{Template(X.Scale(a).Add(X.Scale(b)), X.Scale(ScalarAdd(a, b))), x.Scale(2).Add(y.Scale(5)), nil},
+		{Template(&matrixexp.Pow{M: X, K: 2}, X.Mul(X)), &matrixexp.Pow{M: x, K: 2}, x.Mul(x)},
+		{Template(&matrixexp.Pow{M: X, K: 2}, X.Mul(X)), &matrixexp.Pow{M: x, K: 3}, nil},
+	} {
+		to, err := tt.r.Rewrite(tt.from)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Rewrite(%v) equals %v, want an error", tt.from, to)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Rewrite(%v) returned error %v", tt.from, err)
+			continue
+		}
+		if !matrixexp.StructEqual(to, tt.want) {
+			t.Errorf("Rewrite(%v) equals %v, want %v", tt.from, to, tt.want)
+		}
+	}
+	if s := ScalarString(ScalarMul(a, ScalarAdd(b, 1))); s != "(a * (b + 1))" {
+		t.Errorf("ScalarString equals %v, want %v", s, "(a * (b + 1))")
+	}
+}
+
+func TestTemplateReuse(t *testing.T) {
+	// Applying a rule doesn't change the rule, so it can be applied again.
+	X := new(AnyExp)
+	a, b := Scalar("a"), Scalar("b")
+	r := Template(&matrixexp.Scale{C: a, M: &matrixexp.Scale{C: b, M: X}}, &matrixexp.Scale{C: 1, M: &matrixexp.Scale{C: ScalarMul(a, b), M: X}})
+	x := GeneralRand(3, 3)
+	y := GeneralOnes(3, 3)
+	for _, tt := range []struct {
+		from matrixexp.MatrixExp
+		want matrixexp.MatrixExp
+	}{
+		{&matrixexp.Scale{C: 2, M: x.Scale(3)}, &matrixexp.Scale{C: 1, M: x.Scale(6)}},
+		{&matrixexp.Scale{C: 4, M: y.Scale(5)}, &matrixexp.Scale{C: 1, M: y.Scale(20)}},
+	} {
+		to, err := r.Rewrite(tt.from)
+		if err != nil {
+			t.Errorf("Rewrite(%v) returned error %v", tt.from, err)
+			continue
+		}
+		if !matrixexp.StructEqual(to, tt.want) {
+			t.Errorf("Rewrite(%v) equals %v, want %v", tt.from, to, tt.want)
+		}
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	X, Y := new(AnyExp), new(AnyExp)
+	a := GeneralRand(3, 3)
+	b := GeneralOnes(3, 3)
+	// T of a T simplifies, so double transposes are built directly.
+	tt2 := func(m matrixexp.MatrixExp) matrixexp.MatrixExp {
+		return &matrixexp.T{M: &matrixexp.T{M: m}}
+	}
+	tt := Template(tt2(X), X)
+	swap := Template(X.Add(Y), Y.Add(X))
+	never := Template(&matrixexp.Kron{Left: X, Right: Y}, X)
+
+	for _, c := range []struct {
+		r    Rewriter
+		from matrixexp.MatrixExp
+		want matrixexp.MatrixExp
+	}{
+		{TopDown(tt), tt2(a).Add(tt2(b)), a.Add(b)},
+		{BottomUp(tt), a.Mul(tt2(b)).T(), a.Mul(b).T()},
+		{Innermost(tt), tt2(tt2(a)).Add(b), a.Add(b)},
+		{Sequence(TopDown(tt), swap), tt2(a).Add(b), b.Add(a)},
+		{Choice(never, swap), a.Add(b), b.Add(a)},
+		{Try(never), a.Add(b), a.Add(b)},
+		{Fixpoint(tt), tt2(tt2(a)), a},
+		{Fixpoint(never), a, a},
+	} {
+		to, err := c.r.Rewrite(c.from)
+		if err != nil {
+			t.Errorf("Rewrite(%v) returned error %v", c.from, err)
+			continue
+		}
+		if !matrixexp.StructEqual(to, c.want) {
+			t.Errorf("Rewrite(%v) equals %v, want %v", c.from, to, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		r    Rewriter
+		from matrixexp.MatrixExp
+	}{
+		{TopDown(never), a.Add(b)},
+		{BottomUp(never), a.Add(b)},
+		{Sequence(swap, never), a.Add(b)},
+		{Choice(never, never), a.Add(b)},
+	} {
+		if to, err := c.r.Rewrite(c.from); err == nil {
+			t.Errorf("Rewrite(%v) equals %v, want an error", c.from, to)
+		}
+	}
+
+	// Guards against rules that don't terminate.
+	l := Limit(50)
+	if _, err := l.Fixpoint(swap).Rewrite(a.Add(b)); err == nil {
+		t.Errorf("Fixpoint(swap) returned nil error, want a RewriteCycle")
+	} else if _, ok := err.(*RewriteCycle); !ok {
+		t.Errorf("Fixpoint(swap) returned error %v, want a RewriteCycle", err)
+	}
+	grow := Template(X.T(), tt2(X.T()))
+	for _, r := range []Rewriter{l.Innermost(grow), l.TopDown(grow), Try(l.Fixpoint(l.TopDown(grow)))} {
+		if _, err := r.Rewrite(a.T()); err == nil {
+			t.Errorf("Rewrite of a growing rule returned nil error, want an IterationLimit")
+		} else if _, ok := err.(*IterationLimit); !ok {
+			t.Errorf("Rewrite of a growing rule returned error %v, want an IterationLimit", err)
+		}
+	}
+}
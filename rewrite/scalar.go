@@ -0,0 +1,197 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rewrite
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Scalar wildcards.  A scalar field of an expression, like the coefficient of
+// a Scale, can only hold a float64, so scalar wildcards and the scalar
+// expressions built from them are float64 NaNs with a distinctive payload that
+// identifies them.  In the from side of a Template, a scalar wildcard matches
+// any value, and has to match the same value everywhere it appears.  In the to
+// side, scalar wildcards and expressions are replaced by their values.  Any
+// other scalar in a template only matches an equal value.
+//
+// Because they are NaNs, the usual arithmetic operators can't be used on them:
+// use ScalarAdd, ScalarMul, and so on, or ScalarFunc.  The methods which would
+// combine scalars, like the Scale method of a Scale, keep the nodes separate
+// when either scalar is a NaN, so templates can be built with them.
+
+// scalarTag is the top half of every scalar wildcard, which makes it a quiet
+// NaN that arithmetic is unlikely to produce.
+const scalarTag = 0x7ffa5ca100000000
+
+// scalarNode is a scalar wildcard, or a scalar expression if it has a func.
+type scalarNode struct {
+	name string
+	f    func(args ...float64) float64
+	args []float64
+}
+
+// scalarNodes holds all of the scalar wildcards and expressions, indexed by
+// the bottom half of their bits.
+var scalarNodes struct {
+	sync.Mutex
+	nodes []scalarNode
+	names map[string]float64
+}
+
+// newScalar registers a scalar node.
+func newScalar(n scalarNode) float64 {
+	scalarNodes.Lock()
+	defer scalarNodes.Unlock()
+	scalarNodes.nodes = append(scalarNodes.nodes, n)
+	return math.Float64frombits(scalarTag | uint64(len(scalarNodes.nodes)-1))
+}
+
+// scalarOf returns the scalar node of v, if it is one.
+func scalarOf(v float64) (scalarNode, uint64, bool) {
+	b := math.Float64bits(v)
+	if b&^0xffffffff != scalarTag {
+		return scalarNode{}, 0, false
+	}
+	i := b & 0xffffffff
+	scalarNodes.Lock()
+	defer scalarNodes.Unlock()
+	if i >= uint64(len(scalarNodes.nodes)) {
+		return scalarNode{}, 0, false
+	}
+	return scalarNodes.nodes[i], i, true
+}
+
+// Scalar returns the scalar wildcard with the given name.  Every call with the
+// same name returns the same wildcard.
+func Scalar(name string) float64 {
+	scalarNodes.Lock()
+	v, ok := scalarNodes.names[name]
+	scalarNodes.Unlock()
+	if ok {
+		return v
+	}
+	v = newScalar(scalarNode{name: name})
+	scalarNodes.Lock()
+	defer scalarNodes.Unlock()
+	if w, ok := scalarNodes.names[name]; ok {
+		return w
+	}
+	if scalarNodes.names == nil {
+		scalarNodes.names = make(map[string]float64)
+	}
+	scalarNodes.names[name] = v
+	return v
+}
+
+// ScalarFunc returns a scalar expression, which is f of the values of the
+// arguments, which may be scalar wildcards, scalar expressions, or ordinary
+// numbers.  The name is used to print it.
+func ScalarFunc(name string, f func(args ...float64) float64, args ...float64) float64 {
+	return newScalar(scalarNode{
+		name: name,
+		f:    f,
+		args: append([]float64(nil), args...),
+	})
+}
+
+// ScalarAdd returns the scalar expression a + b.
+func ScalarAdd(a, b float64) float64 {
+	return ScalarFunc("+", func(x ...float64) float64 { return x[0] + x[1] }, a, b)
+}
+
+// ScalarSub returns the scalar expression a - b.
+func ScalarSub(a, b float64) float64 {
+	return ScalarFunc("-", func(x ...float64) float64 { return x[0] - x[1] }, a, b)
+}
+
+// ScalarMul returns the scalar expression a * b.
+func ScalarMul(a, b float64) float64 {
+	return ScalarFunc("*", func(x ...float64) float64 { return x[0] * x[1] }, a, b)
+}
+
+// ScalarDiv returns the scalar expression a / b.
+func ScalarDiv(a, b float64) float64 {
+	return ScalarFunc("/", func(x ...float64) float64 { return x[0] / x[1] }, a, b)
+}
+
+// ScalarString returns a description of a scalar, which may be a scalar
+// wildcard or expression.
+func ScalarString(v float64) string {
+	n, _, ok := scalarOf(v)
+	switch {
+	case !ok:
+		return fmt.Sprint(v)
+	case n.f == nil:
+		return n.name
+	case len(n.args) == 2 && len(n.name) == 1:
+		return "(" + ScalarString(n.args[0]) + " " + n.name + " " + ScalarString(n.args[1]) + ")"
+	}
+	s := n.name + "("
+	for i, a := range n.args {
+		if i > 0 {
+			s += ", "
+		}
+		s += ScalarString(a)
+	}
+	return s + ")"
+}
+
+// ScalarMismatch indicates that a scalar in an expression does not match the
+// scalar in a template.
+type ScalarMismatch struct {
+	expected float64
+	got      float64
+}
+
+// Error implements the error interface.
+func (e *ScalarMismatch) Error() string {
+	return fmt.Sprintf("scalar mismatch: expected %v, got %v", ScalarString(e.expected), e.got)
+}
+
+// matchScalar matches the scalar v with the template scalar from.
+func (m *match) matchScalar(v, from float64) error {
+	n, i, ok := scalarOf(from)
+	switch {
+	case !ok:
+		if v != from {
+			return &ScalarMismatch{from, v}
+		}
+	case n.f != nil:
+		return fmt.Errorf("scalar expression %v can't be matched", ScalarString(from))
+	default:
+		if w, seen := m.scalars[i]; !seen {
+			m.scalars[i] = v
+		} else if math.Float64bits(w) != math.Float64bits(v) {
+			return &ScalarMismatch{w, v}
+		}
+	}
+	return nil
+}
+
+// evalScalar returns the value of a template scalar.
+func (m *match) evalScalar(v float64) (float64, error) {
+	n, i, ok := scalarOf(v)
+	switch {
+	case !ok:
+		return v, nil
+	case n.f == nil:
+		w, seen := m.scalars[i]
+		if !seen {
+			return 0, fmt.Errorf("scalar wildcard %s is not bound", n.name)
+		}
+		return w, nil
+	}
+	args := make([]float64, len(n.args))
+	for j, a := range n.args {
+		w, err := m.evalScalar(a)
+		if err != nil {
+			return 0, err
+		}
+		args[j] = w
+	}
+	return n.f(args...), nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rewrite
+
+import (
+	"fmt"
+	"github.com/jonlawlor/matrixexp"
+	"reflect"
+)
+
+// Rewriting strategies.  A Rewriter like a Template only applies at the root
+// of an expression, and returns an error when it doesn't apply.  Strategies
+// combine rewriters, and apply them throughout an expression.  An error from a
+// rewriter is taken to mean that it doesn't apply, except for the errors of
+// the strategies' guards, IterationLimit and RewriteCycle, which are always
+// passed on.  Like the other rewriters, strategies don't look inside of memos.
+
+// MaxIterations is the Limit of the strategies that don't have one of their
+// own.
+const MaxIterations = 10000
+
+// Limit is the most rewrites that a single TopDown or BottomUp traversal, or
+// steps that a Fixpoint or Innermost, will perform before returning an
+// IterationLimit.  It protects against rule sets that don't terminate.  The
+// strategy functions use MaxIterations, and the methods of a Limit use it
+// instead, as in Limit(100).Innermost(r).
+type Limit int
+
+// Sequence applies each of the rewriters in turn to the result of the one
+// before.  It fails if any of them does.
+func Sequence(rs ...Rewriter) Rewriter {
+	return RewriterFunc(func(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+		for _, r := range rs {
+			var err error
+			if m, err = r.Rewrite(m); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	})
+}
+
+// Choice applies the first of the rewriters which succeeds.  If none of them
+// do, it returns the error of the last one.
+func Choice(rs ...Rewriter) Rewriter {
+	return RewriterFunc(func(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+		err := error(&RuleMismatch{"Choice", m})
+		for _, r := range rs {
+			var to matrixexp.MatrixExp
+			if to, err = r.Rewrite(m); err == nil {
+				return to, nil
+			}
+			if guardError(err) {
+				return nil, err
+			}
+		}
+		return nil, err
+	})
+}
+
+// Try applies a rewriter, or leaves the expression unchanged if it doesn't
+// apply.  It never fails, except for the errors of the guards.
+func Try(r Rewriter) Rewriter {
+	return RewriterFunc(func(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+		to, err := r.Rewrite(m)
+		if err != nil {
+			if guardError(err) {
+				return nil, err
+			}
+			return m, nil
+		}
+		return to, nil
+	})
+}
+
+// TopDown applies a rewriter once to every subexpression of an expression,
+// starting with the root, and then moving on to the subexpressions of the
+// result.  It fails if the rewriter doesn't apply anywhere.
+func TopDown(r Rewriter) Rewriter {
+	return Limit(MaxIterations).TopDown(r)
+}
+
+// TopDown is the TopDown strategy, with this limit.
+func (l Limit) TopDown(r Rewriter) Rewriter {
+	return RewriterFunc(func(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+		t := &traversal{r: r, name: "TopDown", pre: true, limit: l}
+		return t.run(m)
+	})
+}
+
+// BottomUp applies a rewriter once to every subexpression of an expression,
+// starting with the leaves, so that each expression is rewritten after its
+// subexpressions.  It fails if the rewriter doesn't apply anywhere.
+func BottomUp(r Rewriter) Rewriter {
+	return Limit(MaxIterations).BottomUp(r)
+}
+
+// BottomUp is the BottomUp strategy, with this limit.
+func (l Limit) BottomUp(r Rewriter) Rewriter {
+	return RewriterFunc(func(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+		t := &traversal{r: r, name: "BottomUp", limit: l}
+		return t.run(m)
+	})
+}
+
+// Fixpoint applies a rewriter repeatedly, until it no longer applies or no
+// longer changes the expression.  It never fails, except for the errors of
+// the guards: an IterationLimit after MaxIterations steps, or a RewriteCycle if
+// the rewriter produces an expression it has already produced.
+func Fixpoint(r Rewriter) Rewriter {
+	return Limit(MaxIterations).Fixpoint(r)
+}
+
+// Fixpoint is the Fixpoint strategy, with this limit.
+func (l Limit) Fixpoint(r Rewriter) Rewriter {
+	return RewriterFunc(func(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+		seen := make(map[uint64][]matrixexp.MatrixExp)
+		see := func(m matrixexp.MatrixExp) bool {
+			h := matrixexp.Hash(m)
+			for _, s := range seen[h] {
+				if matrixexp.StructEqual(m, s) {
+					return true
+				}
+			}
+			seen[h] = append(seen[h], m)
+			return false
+		}
+		see(m)
+		for i := 0; i < int(l); i++ {
+			to, err := r.Rewrite(m)
+			if err != nil {
+				if guardError(err) {
+					return nil, err
+				}
+				return m, nil
+			}
+			if matrixexp.StructEqual(to, m) {
+				return to, nil
+			}
+			if see(to) {
+				return nil, &RewriteCycle{to}
+			}
+			m = to
+		}
+		return nil, &IterationLimit{"Fixpoint", l, m}
+	})
+}
+
+// Innermost rewrites an expression to a normal form, where the rewriter
+// doesn't apply to any of its subexpressions, by applying it bottom up until
+// it doesn't apply anywhere.  Like Fixpoint, it never fails, except for the
+// errors of the guards.
+func Innermost(r Rewriter) Rewriter {
+	return Limit(MaxIterations).Innermost(r)
+}
+
+// Innermost is the Innermost strategy, with this limit.
+func (l Limit) Innermost(r Rewriter) Rewriter {
+	return l.Fixpoint(l.BottomUp(r))
+}
+
+// traversal holds the state of a TopDown or BottomUp rewrite.
+type traversal struct {
+	r       Rewriter
+	name    string
+	pre     bool // rewrite parents before their children
+	limit   Limit
+	applied int
+}
+
+// run rewrites m, and fails if nothing was rewritten.
+func (t *traversal) run(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	to, err := t.visit(m)
+	if err != nil {
+		return nil, err
+	}
+	if t.applied == 0 {
+		return nil, &RuleMismatch{t.name, m}
+	}
+	return to, nil
+}
+
+// apply applies the rewriter to m, if it can.
+func (t *traversal) apply(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	to, err := t.r.Rewrite(m)
+	if err != nil {
+		if guardError(err) {
+			return nil, err
+		}
+		return m, nil
+	}
+	if t.applied++; t.applied > int(t.limit) {
+		return nil, &IterationLimit{t.name, t.limit, m}
+	}
+	return to, nil
+}
+
+// visit rewrites m and its subexpressions.
+func (t *traversal) visit(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	var err error
+	if t.pre {
+		if m, err = t.apply(m); err != nil {
+			return nil, err
+		}
+	}
+	if m, err = t.children(m); err != nil {
+		return nil, err
+	}
+	if !t.pre {
+		if m, err = t.apply(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// children rewrites the subexpressions of m, and returns a copy of m with the
+// results if any of them changed.
+func (t *traversal) children(m matrixexp.MatrixExp) (matrixexp.MatrixExp, error) {
+	rm := reflect.ValueOf(m)
+	if _, ok := m.(matrixexp.MatrixLiteral); ok || rm.Kind() != reflect.Ptr || rm.Elem().Kind() != reflect.Struct {
+		return m, nil
+	}
+	if _, ok := m.(*matrixexp.Memo); ok {
+		return m, nil
+	}
+	cp := reflect.New(rm.Elem().Type())
+	cp.Elem().Set(rm.Elem())
+	changed := false
+	for i := 0; i < cp.Elem().NumField(); i++ {
+		f := cp.Elem().Field(i)
+		if !f.CanSet() || !isExpField(f) {
+			continue
+		}
+		c := f.Interface().(matrixexp.MatrixExp)
+		to, err := t.visit(c)
+		if err != nil {
+			return nil, err
+		}
+		if to != c {
+			f.Set(reflect.ValueOf(to))
+			changed = true
+		}
+	}
+	if !changed {
+		return m, nil
+	}
+	return cp.Interface().(matrixexp.MatrixExp), nil
+}
+
+// guardError determines if err is from one of the guards of a strategy, which
+// can't be ignored.
+func guardError(err error) bool {
+	switch err.(type) {
+	case *IterationLimit, *RewriteCycle:
+		return true
+	}
+	return false
+}
+
+// IterationLimit indicates that a strategy gave up after its Limit of
+// rewrites, which usually means that its rules don't terminate.
+type IterationLimit struct {
+	strategy string
+	limit    Limit
+	got      matrixexp.MatrixExp
+}
+
+// Error implements the error interface.
+func (e *IterationLimit) Error() string {
+	return fmt.Sprintf("%s gave up after %d rewrites of %v", e.strategy, e.limit, e.got)
+}
+
+// RewriteCycle indicates that a strategy rewrote an expression back into one
+// that it had already produced, so it would never finish.
+type RewriteCycle struct {
+	got matrixexp.MatrixExp
+}
+
+// Error implements the error interface.
+func (e *RewriteCycle) Error() string {
+	return fmt.Sprintf("rewrite cycle: %v was produced twice", e.got)
+}
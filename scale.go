@@ -5,7 +5,8 @@
 package matrixexp
 
 import (
-	"github.com/gonum/blas/blas64"
+	"context"
+	"math"
 	"strconv"
 )
 
@@ -33,23 +34,48 @@ func (m1 *Scale) At(r, c int) float64 {
 
 // Eval returns a matrix literal.
 func (m1 *Scale) Eval() MatrixLiteral {
-	r, c := m1.Dims()
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Scale) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *Scale) evalCtx(ctx context.Context) MatrixLiteral {
+	return fuseEval(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Scale) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *Scale) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	return fuse(ctx, m1, dst)
+}
+
+// operands returns the operands of the element-wise expression.
+func (m1 *Scale) operands() []MatrixExp {
+	return []MatrixExp{m1.M}
+}
 
-	mv := m1.M.Eval()
-	v1 := mv.AsVector()
+// combine computes a row of the element-wise expression.
+func (m1 *Scale) combine(dst []float64, args [][]float64) {
 	C := m1.C
-	for i := range v1 {
-		v1[i] *= C
+	for j, v := range args[0] {
+		dst[j] = v * C
 	}
-
-	return &General{blas64.General{
-		Rows:   r,
-		Cols:   c,
-		Stride: c,
-		Data:   v1,
-	}}
 }
 
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Scale) ownedResult() {}
+
 // Copy creates a (deep) copy of the Matrix Expression.
 func (m1 *Scale) Copy() MatrixExp {
 	return &Scale{
@@ -84,8 +110,16 @@ func (m1 *Scale) Sub(m2 MatrixExp) MatrixExp {
 	}
 }
 
-// Scale performs scalar multiplication.
+// Scale performs scalar multiplication.  The coefficients are combined, unless
+// either of them is a NaN, which may be a scalar wildcard of the rewrite
+// package that has to stay on its own node.
 func (m1 *Scale) Scale(c float64) MatrixExp {
+	if math.IsNaN(c) || math.IsNaN(m1.C) {
+		return &Scale{
+			C: c,
+			M: m1,
+		}
+	}
 	return &Scale{
 		C: c * m1.C,
 		M: m1.M,
@@ -115,3 +149,8 @@ func (m1 *Scale) DivElem(m2 MatrixExp) MatrixExp {
 		Right: m2,
 	}
 }
+
+// Inv inverts a matrix.
+func (m1 *Scale) Inv() MatrixExp {
+	return &Inv{m1}
+}
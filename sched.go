@@ -0,0 +1,101 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+)
+
+// A Scheduler runs the evaluation of Futures.  Schedulers must be safe for
+// concurrent use.
+type Scheduler interface {
+	// Go runs f, either on another goroutine or before it returns.  It must
+	// not wait for any other function passed to Go, because f may itself be
+	// waiting on one.
+	Go(f func())
+}
+
+// Inline is a Scheduler that runs every function before Go returns, so that
+// Futures are evaluated as soon as they are created.
+var Inline Scheduler = inlineScheduler{}
+
+type inlineScheduler struct{}
+
+func (inlineScheduler) Go(f func()) { f() }
+
+// WorkerPool is a Scheduler that runs functions on at most a fixed number of
+// goroutines at a time.  When all of them are busy, Go runs the function
+// itself instead of waiting for one to be free, so a Future which is evaluated
+// by the pool and depends on other Futures can't deadlock it.
+type WorkerPool struct {
+	sem chan struct{}
+}
+
+// NewWorkerPool creates a WorkerPool with n workers.  If n is less than 1,
+// the pool has a worker for each of GOMAXPROCS.
+func NewWorkerPool(n int) *WorkerPool {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &WorkerPool{
+		sem: make(chan struct{}, n),
+	}
+}
+
+// Go runs f on a worker, or runs it before returning if they are all busy.
+func (p *WorkerPool) Go(f func()) {
+	select {
+	case p.sem <- struct{}{}:
+		go func() {
+			defer func() { <-p.sem }()
+			f()
+		}()
+	default:
+		f()
+	}
+}
+
+// scheduler holds the current Scheduler.
+var scheduler atomic.Value
+
+type schedulerBox struct {
+	Scheduler
+}
+
+func init() {
+	scheduler.Store(schedulerBox{NewWorkerPool(0)})
+}
+
+// SetScheduler changes the Scheduler used to evaluate Futures.  A nil
+// Scheduler is the same as Inline.
+func SetScheduler(s Scheduler) {
+	if s == nil {
+		s = Inline
+	}
+	scheduler.Store(schedulerBox{s})
+}
+
+type schedulerKey struct{}
+
+// WithScheduler returns a context which evaluates Futures with a different
+// Scheduler, such as a smaller WorkerPool, instead of the one set by
+// SetScheduler.  It applies to every Future created by an evaluation under
+// the context.
+func WithScheduler(ctx context.Context, s Scheduler) context.Context {
+	if s == nil {
+		s = Inline
+	}
+	return context.WithValue(ctx, schedulerKey{}, s)
+}
+
+// getScheduler returns the Scheduler to use under ctx.
+func getScheduler(ctx context.Context) Scheduler {
+	if s, ok := ctx.Value(schedulerKey{}).(Scheduler); ok {
+		return s
+	}
+	return scheduler.Load().(schedulerBox).Scheduler
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Parallel()
+	p := NewWorkerPool(1)
+	block := make(chan struct{})
+	done := make(chan struct{})
+	p.Go(func() {
+		<-block
+		close(done)
+	})
+
+	// The only worker is busy, so this has to run before Go returns.
+	ran := false
+	p.Go(func() { ran = true })
+	if !ran {
+		t.Errorf("WorkerPool.Go did not run a function while saturated")
+	}
+	close(block)
+	<-done
+}
+
+func TestWithScheduler(t *testing.T) {
+	t.Parallel()
+	a := &General{rnd(4, 4)}
+	b := &General{rnd(4, 4)}
+	m := &Async{(&Async{a.Mul(b)}).Add(&Async{b.Mul(a)}).Mul(&Async{a})}
+	want := a.Mul(b).Add(b.Mul(a)).Mul(a).Eval()
+	for _, s := range []Scheduler{Inline, NewWorkerPool(1), NewWorkerPool(2)} {
+		ctx := WithScheduler(context.Background(), s)
+		got, err := m.EvalContext(ctx)
+		if err != nil {
+			t.Errorf("%v.EvalContext returned error %v", m, err)
+			continue
+		}
+		if !EqualsApprox(got, want, Tolerance{Tol: 1e-12}) {
+			t.Errorf("%v.EvalContext equals %v, want %v", m, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"math"
+	"testing"
+)
+
+// StructuredMatrices are implicit matrices defined by generating vectors.
+var StructuredMatrices = []struct {
+	m    MatrixExp
+	want [][]float64
+}{
+	{
+		m:    &Toeplitz{Col: []float64{1, 2, 3}, Row: []float64{1, 4, 5, 6}},
+		want: [][]float64{{1, 4, 5, 6}, {2, 1, 4, 5}, {3, 2, 1, 4}},
+	},
+	{
+		m:    &Toeplitz{Col: []float64{1, 2, 3, 7, 8}, Row: []float64{1, 4}},
+		want: [][]float64{{1, 4}, {2, 1}, {3, 2}, {7, 3}, {8, 7}},
+	},
+	{
+		m:    &Circulant{C: []float64{1, 2, 3}},
+		want: [][]float64{{1, 3, 2}, {2, 1, 3}, {3, 2, 1}},
+	},
+	{
+		m:    &Circulant{C: []float64{1, -2, 3, 0.5, 7}},
+		want: [][]float64{{1, 7, 0.5, 3, -2}, {-2, 1, 7, 0.5, 3}, {3, -2, 1, 7, 0.5}, {0.5, 3, -2, 1, 7}, {7, 0.5, 3, -2, 1}},
+	},
+	{
+		m:    &Hankel{Col: []float64{1, 2, 3}, Row: []float64{3, 4, 5, 6}},
+		want: [][]float64{{1, 2, 3, 4}, {2, 3, 4, 5}, {3, 4, 5, 6}},
+	},
+	{
+		m:    &Hankel{Col: []float64{1, 2, 3, 4}, Row: []float64{4, 5}},
+		want: [][]float64{{1, 2}, {2, 3}, {3, 4}, {4, 5}},
+	},
+	{
+		m:    &Vandermonde{X: []float64{1, 2, -3}, N: 4},
+		want: [][]float64{{1, 1, 1, 1}, {1, 2, 4, 8}, {1, -3, 9, -27}},
+	},
+}
+
+// dense creates a General from a slice of rows.
+func dense(rows [][]float64) *General {
+	g := &General{zeros(len(rows), len(rows[0]))}
+	for i, row := range rows {
+		for j, v := range row {
+			g.Set(i, j, v)
+		}
+	}
+	return g
+}
+
+func TestStructured(t *testing.T) {
+	t.Parallel()
+	for _, tt := range StructuredMatrices {
+		if err := tt.m.Err(); err != nil {
+			t.Errorf("%v.Err() equals %v, want nil", tt.m, err)
+		}
+		want := dense(tt.want)
+		if got := tt.m.Eval(); !Equals(got, want) {
+			t.Errorf("%v equals %v, want %v", tt.m, got, want)
+		}
+		r, c := want.Dims()
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if got := tt.m.At(i, j); got != want.At(i, j) {
+					t.Errorf("%v.At(%d, %d) equals %v, want %v", tt.m, i, j, got, want.At(i, j))
+				}
+			}
+		}
+
+		x := GeneralRand(c, 3)
+		for _, op := range []struct {
+			got, want MatrixExp
+		}{
+			{got: tt.m.Mul(x), want: want.Mul(x)},
+			{got: tt.m.T(), want: want.T()},
+			{got: tt.m.Scale(-2), want: want.Scale(-2)},
+			{got: tt.m.Add(tt.m), want: want.Add(want)},
+			{got: tt.m.Sub(tt.m.Scale(3)), want: want.Sub(want.Scale(3))},
+		} {
+			w := op.want.Eval()
+			g := op.got.Eval()
+			rr, cc := w.Dims()
+			if gr, gc := g.Dims(); gr != rr || gc != cc {
+				t.Errorf("%v has dims (%d, %d), want (%d, %d)", op.got, gr, gc, rr, cc)
+				continue
+			}
+			for i := 0; i < rr; i++ {
+				for j := 0; j < cc; j++ {
+					if math.Abs(g.At(i, j)-w.At(i, j)) > 1e-12 {
+						t.Errorf("%v.At(%d, %d) equals %v, want %v", op.got, i, j, g.At(i, j), w.At(i, j))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestStructuredErr(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		m   MatrixExp
+		err error
+	}{
+		{m: &Toeplitz{}, err: nil},
+		{m: &Toeplitz{Col: []float64{1, 2}, Row: []float64{1, 3}}, err: nil},
+		{m: &Toeplitz{Col: []float64{1, 2}, Row: []float64{4, 3}}, err: ErrSharedElement{Col: 2, Row: 2, CV: 1, RV: 4}},
+		{m: &Toeplitz{Col: []float64{1, 2}}, err: ErrSharedElement{Col: 2, Row: 0}},
+		{m: &Toeplitz{Row: []float64{1, 2, 3}}, err: ErrSharedElement{Col: 0, Row: 3}},
+		{m: &Hankel{Col: []float64{1, 2}, Row: []float64{2, 3, 4}}, err: nil},
+		{m: &Hankel{Col: []float64{1, 2}, Row: []float64{1, 3, 4}}, err: ErrSharedElement{Col: 2, Row: 3, CV: 2, RV: 1}},
+		{m: &Hankel{Row: []float64{1}}, err: ErrSharedElement{Col: 0, Row: 1}},
+		{m: &Circulant{}, err: nil},
+	} {
+		if err := tt.m.Err(); err != tt.err {
+			t.Errorf("%v.Err() equals %v, want %v", tt.m, err, tt.err)
+		}
+	}
+}
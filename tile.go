@@ -0,0 +1,126 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Tiling controls how element-wise expressions and transposes are split into
+// square tiles, which are small enough to stay in cache while they are
+// evaluated, and which are evaluated in parallel when there is more than one.
+type Tiling struct {
+	// Size is the number of rows and columns in a tile.  If it is less than 1,
+	// it is DefaultTileSize.
+	Size int
+
+	// Workers is the maximum number of goroutines that evaluate the tiles of
+	// a single matrix.  If it is less than 1, it is GOMAXPROCS.  If it is 1,
+	// the tiles are evaluated one after another.
+	Workers int
+}
+
+// DefaultTileSize is the tile size used when it isn't set.  A tile of 64 by 64
+// float64s fits in a typical 32KB L1 cache.
+const DefaultTileSize = 64
+
+type tilingKey struct{}
+
+// WithTiling returns a context which evaluates expressions under it with a
+// different tiling.
+func WithTiling(ctx context.Context, t Tiling) context.Context {
+	return context.WithValue(ctx, tilingKey{}, t)
+}
+
+// getTiling returns the tiling to use under ctx, with any defaults filled in.
+func getTiling(ctx context.Context) Tiling {
+	t, _ := ctx.Value(tilingKey{}).(Tiling)
+	if t.Size < 1 {
+		t.Size = DefaultTileSize
+	}
+	if t.Workers < 1 {
+		t.Workers = runtime.GOMAXPROCS(0)
+	}
+	return t
+}
+
+// forTiles splits an r by c matrix into tiles, and calls a function for each
+// tile with the rows [i0, i1) and columns [j0, j1) that it covers.  Each
+// worker gets its own function from newWorker, which is called before any of
+// the tiles are evaluated, with the largest width of a tile.  If a worker
+// panics, the remaining tiles are skipped and the panic is passed on to the
+// caller.
+func forTiles(ctx context.Context, r, c int, newWorker func(width int) func(i0, i1, j0, j1 int)) {
+	t := getTiling(ctx)
+	rows := (r + t.Size - 1) / t.Size
+	cols := (c + t.Size - 1) / t.Size
+	n := rows * cols
+	width := t.Size
+	if c < width {
+		width = c
+	}
+	tile := func(fn func(i0, i1, j0, j1 int), k int) {
+		i0, j0 := (k/cols)*t.Size, (k%cols)*t.Size
+		i1, j1 := i0+t.Size, j0+t.Size
+		if i1 > r {
+			i1 = r
+		}
+		if j1 > c {
+			j1 = c
+		}
+		fn(i0, i1, j0, j1)
+	}
+
+	workers := t.Workers
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		fn := newWorker(width)
+		for k := 0; k < n; k++ {
+			check(ctx)
+			tile(fn, k)
+		}
+		return
+	}
+
+	fns := make([]func(i0, i1, j0, j1 int), workers)
+	for w := range fns {
+		fns[w] = newWorker(width)
+	}
+	var (
+		next    int64 = -1
+		stopped int32
+		wg      sync.WaitGroup
+		once    sync.Once
+		failure interface{}
+	)
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn func(i0, i1, j0, j1 int)) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					atomic.StoreInt32(&stopped, 1)
+					once.Do(func() { failure = r })
+				}
+			}()
+			for k := int(atomic.AddInt64(&next, 1)); k < n; k = int(atomic.AddInt64(&next, 1)) {
+				if atomic.LoadInt32(&stopped) != 0 {
+					return
+				}
+				check(ctx)
+				tile(fn, k)
+			}
+		}(fn)
+	}
+	wg.Wait()
+	if failure != nil {
+		panic(failure)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTiling(t *testing.T) {
+	t.Parallel()
+	x := &General{rnd(7, 5)}
+	y := &General{rnd(7, 5)}
+	s := &General{rnd(9, 9)}
+	exps := []MatrixExp{
+		x.Add(y).MulElem(x).Scale(2).Sub(y).DivElem(x),
+		x.T(),
+		x.Add(y).T(),
+		s.Scale(2).T(),
+		s.Mul(s).Add(s).T().Sub(s),
+	}
+	for _, m := range exps {
+		want := &General{zeros(m.Dims())}
+		for i := 0; i < want.Rows; i++ {
+			for j := 0; j < want.Cols; j++ {
+				want.Set(i, j, m.At(i, j))
+			}
+		}
+		for _, tiling := range []Tiling{{}, {Size: 1}, {Size: 2, Workers: 1}, {Size: 3, Workers: 4}, {Size: 4, Workers: 16}} {
+			got, err := m.EvalContext(WithTiling(context.Background(), tiling))
+			if err != nil {
+				t.Fatalf("%v.EvalContext with %v returned error %v", m, tiling, err)
+			}
+			if !EqualsApprox(got, want, Tolerance{Tol: 1e-12}) {
+				t.Errorf("%v.EvalContext with %v equals %v, want %v", m, tiling, got, want)
+			}
+		}
+	}
+}
+
+func TestTilingCancel(t *testing.T) {
+	t.Parallel()
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(WithTiling(context.Background(), Tiling{Size: 2, Workers: workers}))
+		var tiles int32
+		func() {
+			defer func() {
+				if r := recover(); r != (abort{context.Canceled}) {
+					t.Errorf("forTiles with %v workers panicked with %v, want %v", workers, r, abort{context.Canceled})
+				}
+			}()
+			forTiles(ctx, 100, 100, func(int) func(i0, i1, j0, j1 int) {
+				return func(i0, i1, j0, j1 int) {
+					if atomic.AddInt32(&tiles, 1) == 1 {
+						cancel()
+					}
+				}
+			})
+		}()
+		if n := atomic.LoadInt32(&tiles); n >= 2500 {
+			t.Errorf("forTiles with %v workers evaluated %v tiles after it was cancelled", workers, n)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"fmt"
+	"github.com/gonum/blas/blas64"
+	"math"
+)
+
+// Toeplitz represents a matrix which is constant along each diagonal.  It is
+// defined by its first column and first row, which must agree on the value
+// of the main diagonal.
+type Toeplitz struct {
+	Col []float64
+	Row []float64
+}
+
+// String implements the Stringer interface.
+func (m1 *Toeplitz) String() string {
+	return fmt.Sprintf("Toeplitz{%v, %v}", m1.Col, m1.Row)
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Toeplitz) Dims() (r, c int) {
+	r, c = len(m1.Col), len(m1.Row)
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Toeplitz) At(r, c int) float64 {
+	if r >= c {
+		return m1.Col[r-c]
+	}
+	return m1.Row[c-r]
+}
+
+// Eval returns a matrix literal.
+func (m1 *Toeplitz) Eval() MatrixLiteral {
+	r, c := m1.Dims()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   c,
+		Stride: c,
+		Data:   getAllocator().Get(r * c),
+	}
+	for i := 0; i < r; i++ {
+		row := m.Data[i*c : (i+1)*c]
+		for j := range row {
+			row[j] = m1.At(i, j)
+		}
+	}
+	return &General{m}
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Toeplitz) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Toeplitz) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// leftMul multiplies the Toeplitz matrix by a matrix literal.  Each column of
+// the product is a linear convolution with the diagonals of the matrix, which
+// is found with the FFT.
+func (m1 *Toeplitz) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	r, c := m1.Dims()
+	x := m2.AsGeneral()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(r * x.Cols),
+	}
+	if r == 0 || c == 0 {
+		return &General{m}
+	}
+
+	// diagonal i - j of the matrix is stored at g[i-j+c-1].
+	g := make([]float64, r+c-1)
+	for d := 1; d < c; d++ {
+		g[c-1-d] = m1.Row[d]
+	}
+	copy(g[c-1:], m1.Col)
+
+	cv := newConvolver(g, c)
+	for j := 0; j < x.Cols; j++ {
+		check(ctx)
+		y := cv.convolve(column(x, j))
+		for i := 0; i < r; i++ {
+			m.Data[i*m.Stride+j] = y[i+c-1]
+		}
+	}
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Toeplitz) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Toeplitz) Copy() MatrixExp {
+	return &Toeplitz{
+		Col: scaleCopy(1, m1.Col),
+		Row: scaleCopy(1, m1.Row),
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Toeplitz) Err() error {
+	if len(m1.Col) == 0 && len(m1.Row) == 0 {
+		return nil
+	}
+	if len(m1.Col) == 0 || len(m1.Row) == 0 {
+		return ErrSharedElement{Col: len(m1.Col), Row: len(m1.Row)}
+	}
+	return sharedElement(m1.Col, m1.Row, m1.Col[0])
+}
+
+// sharedElement checks that the element v of col, which it shares with row, is
+// the same as the first element of row.
+func sharedElement(col, row []float64, v float64) error {
+	if rv := row[0]; v != rv && !(math.IsNaN(v) && math.IsNaN(rv)) {
+		return ErrSharedElement{
+			Col: len(col),
+			Row: len(row),
+			CV:  v,
+			RV:  rv,
+		}
+	}
+	return nil
+}
+
+// T transposes a matrix.  The transpose of a Toeplitz matrix is Toeplitz, with
+// the first row and column swapped.
+func (m1 *Toeplitz) T() MatrixExp {
+	t := &Toeplitz{
+		Col: scaleCopy(1, m1.Row),
+		Row: scaleCopy(1, m1.Col),
+	}
+	if len(t.Col) > 0 && len(t.Row) > 0 {
+		t.Col[0] = t.Row[0]
+	}
+	return t
+}
+
+// Add two matrices together.  The sum of two Toeplitz matrices is Toeplitz.
+func (m1 *Toeplitz) Add(m2 MatrixExp) MatrixExp {
+	if t, ok := m2.(*Toeplitz); ok && sameDims(m1, t) {
+		return &Toeplitz{
+			Col: addCopy(m1.Col, 1, t.Col),
+			Row: addCopy(m1.Row, 1, t.Row),
+		}
+	}
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.  The difference of two
+// Toeplitz matrices is Toeplitz.
+func (m1 *Toeplitz) Sub(m2 MatrixExp) MatrixExp {
+	if t, ok := m2.(*Toeplitz); ok && sameDims(m1, t) {
+		return &Toeplitz{
+			Col: addCopy(m1.Col, -1, t.Col),
+			Row: addCopy(m1.Row, -1, t.Row),
+		}
+	}
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Toeplitz) Scale(c float64) MatrixExp {
+	return &Toeplitz{
+		Col: scaleCopy(c, m1.Col),
+		Row: scaleCopy(c, m1.Row),
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Toeplitz) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Toeplitz) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Toeplitz) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Toeplitz) Inv() MatrixExp {
+	return &Inv{m1}
+}
+
+// scaleCopy returns a copy of v multiplied by c.
+func scaleCopy(c float64, v []float64) []float64 {
+	w := make([]float64, len(v))
+	for i, x := range v {
+		w[i] = c * x
+	}
+	return w
+}
+
+// addCopy returns a + alpha * b, where a and b have the same length.
+func addCopy(a []float64, alpha float64, b []float64) []float64 {
+	w := make([]float64, len(a))
+	for i, x := range a {
+		w[i] = x + alpha*b[i]
+	}
+	return w
+}
+
+// column returns a copy of column j of a.
+func column(a blas64.General, j int) []float64 {
+	v := make([]float64, a.Rows)
+	for i := range v {
+		v[i] = a.Data[i*a.Stride+j]
+	}
+	return v
+}
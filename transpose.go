@@ -5,6 +5,7 @@
 package matrixexp
 
 import (
+	"context"
 	"github.com/gonum/blas/blas64"
 )
 
@@ -31,23 +32,89 @@ func (m1 *T) At(r, c int) float64 {
 
 // Eval returns a matrix literal.
 func (m1 *T) Eval() MatrixLiteral {
-	mr, mc := m1.M.Dims()
-	mv := m1.M.Eval().AsVector()
-	v := make([]float64, len(mv))
-	for i := 0; i < mr; i++ {
-		for j := 0; j < mc; j++ {
-			v[j*mr+i] = mv[i*mc+j]
-		}
+	return m1.evalCtx(background())
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *T) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalContext(ctx, m1)
+}
+
+// evalCtx evaluates the matrix expression under ctx.
+func (m1 *T) evalCtx(ctx context.Context) MatrixLiteral {
+	mv := evalCtx(ctx, m1.M)
+	if g, ok := temporary(m1.M, mv); ok && g.Rows == g.Cols {
+		// A square temporary can be transposed in place.
+		transposeSquare(ctx, g)
+		return mv
+	}
+	r, c := m1.Dims()
+	m := newGeneral(r, c)
+	transposeInto(ctx, m.General, mv.AsGeneral())
+	release(m1.M, mv)
+	return m
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *T) EvalInto(dst MatrixLiteral) error {
+	return m1.evalIntoCtx(background(), dst)
+}
+
+// evalIntoCtx evaluates the matrix expression into dst under ctx.
+func (m1 *T) evalIntoCtx(ctx context.Context, dst MatrixLiteral) error {
+	if err := checkDst(m1, dst); err != nil {
+		return err
 	}
+	d := dst.AsGeneral()
+	mv := evalCtx(ctx, m1.M)
+	transposeInto(ctx, d, disjoint(d, mv.AsGeneral()))
+	release(m1.M, mv)
+	return nil
+}
 
-	return &General{blas64.General{
-		Rows:   mc,
-		Cols:   mr,
-		Stride: mr,
-		Data:   v,
-	}}
+// transposeInto sets dst to the transpose of a, which must not share memory.
+// It works one tile at a time, so that both the rows of a tile of a and the
+// rows of the tile of dst that it is written to stay in cache.
+func transposeInto(ctx context.Context, dst, a blas64.General) {
+	forTiles(ctx, a.Rows, a.Cols, func(int) func(i0, i1, j0, j1 int) {
+		return func(i0, i1, j0, j1 int) {
+			for i := i0; i < i1; i++ {
+				for j, v := range a.Data[i*a.Stride+j0 : i*a.Stride+j1] {
+					dst.Data[(j0+j)*dst.Stride+i] = v
+				}
+			}
+		}
+	})
 }
 
+// transposeSquare transposes the square matrix a in place, by swapping each
+// tile above the diagonal with the transposed tile below it.
+func transposeSquare(ctx context.Context, a blas64.General) {
+	forTiles(ctx, a.Rows, a.Cols, func(int) func(i0, i1, j0, j1 int) {
+		return func(i0, i1, j0, j1 int) {
+			if i0 > j0 {
+				return
+			}
+			for i := i0; i < i1; i++ {
+				j := j0
+				if i0 == j0 {
+					// Tiles on the diagonal are transposed in place.
+					j = i + 1
+				}
+				for ; j < j1; j++ {
+					a.Data[i*a.Stride+j], a.Data[j*a.Stride+i] = a.Data[j*a.Stride+i], a.Data[i*a.Stride+j]
+				}
+			}
+		}
+	})
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *T) ownedResult() {}
+
 // Copy creates a (deep) copy of the Matrix Expression.
 func (m1 *T) Copy() MatrixExp {
 	return &T{
@@ -112,3 +179,8 @@ func (m1 *T) DivElem(m2 MatrixExp) MatrixExp {
 		Right: m2,
 	}
 }
+
+// Inv inverts a matrix.
+func (m1 *T) Inv() MatrixExp {
+	return &Inv{m1}
+}
@@ -0,0 +1,168 @@
+// Copyright 2015 Jonathan J Lawlor. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package matrixexp
+
+import (
+	"context"
+	"fmt"
+	"github.com/gonum/blas/blas64"
+	"math"
+)
+
+// Vandermonde represents a matrix with rows that are the powers of X, from
+// X[i]^0 to X[i]^(N-1).
+type Vandermonde struct {
+	X []float64
+	N int
+}
+
+// String implements the Stringer interface.
+func (m1 *Vandermonde) String() string {
+	return fmt.Sprintf("Vandermonde{%v, %d}", m1.X, m1.N)
+}
+
+// Dims returns the matrix dimensions.
+func (m1 *Vandermonde) Dims() (r, c int) {
+	r, c = len(m1.X), m1.N
+	return
+}
+
+// At returns the value at a given row, column index.
+func (m1 *Vandermonde) At(r, c int) float64 {
+	return math.Pow(m1.X[r], float64(c))
+}
+
+// Eval returns a matrix literal.
+func (m1 *Vandermonde) Eval() MatrixLiteral {
+	r, c := m1.Dims()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   c,
+		Stride: c,
+		Data:   getAllocator().Get(r * c),
+	}
+	for i, x := range m1.X {
+		v := 1.0
+		for j := 0; j < c; j++ {
+			m.Data[i*c+j] = v
+			v *= x
+		}
+	}
+	return &General{m}
+}
+
+// EvalContext evaluates the matrix expression like Eval, but stops early and
+// returns the context's error if the context is done first.
+func (m1 *Vandermonde) EvalContext(ctx context.Context) (MatrixLiteral, error) {
+	return evalLiteral(ctx, m1)
+}
+
+// EvalInto evaluates the matrix expression into an existing matrix literal,
+// which must have the same dimensions.
+func (m1 *Vandermonde) EvalInto(dst MatrixLiteral) error {
+	return evalInto(background(), m1, dst)
+}
+
+// leftMul multiplies the Vandermonde matrix by a matrix literal.  Each element
+// of the product is a polynomial in X[i], which is found with Horner's method.
+func (m1 *Vandermonde) leftMul(ctx context.Context, m2 MatrixLiteral) MatrixLiteral {
+	r, c := m1.Dims()
+	x := m2.AsGeneral()
+	m := blas64.General{
+		Rows:   r,
+		Cols:   x.Cols,
+		Stride: x.Cols,
+		Data:   getAllocator().Get(r * x.Cols),
+	}
+	for i, xi := range m1.X {
+		check(ctx)
+		row := m.Data[i*m.Stride : i*m.Stride+x.Cols]
+		for k := c - 1; k >= 0; k-- {
+			coef := x.Data[k*x.Stride : k*x.Stride+x.Cols]
+			for j := range row {
+				row[j] = row[j]*xi + coef[j]
+			}
+		}
+	}
+	return &General{m}
+}
+
+// ownedResult marks the result of evaluating the expression as a new matrix,
+// which the caller owns.
+func (m1 *Vandermonde) ownedResult() {}
+
+// Copy creates a (deep) copy of the Matrix Expression.
+func (m1 *Vandermonde) Copy() MatrixExp {
+	return &Vandermonde{
+		X: scaleCopy(1, m1.X),
+		N: m1.N,
+	}
+}
+
+// Err returns the first error encountered while constructing the matrix expression.
+func (m1 *Vandermonde) Err() error {
+	if m1.N < 0 {
+		return ErrInvalidCols(m1.N)
+	}
+	return nil
+}
+
+// T transposes a matrix.
+func (m1 *Vandermonde) T() MatrixExp {
+	return &T{m1}
+}
+
+// Add two matrices together.
+func (m1 *Vandermonde) Add(m2 MatrixExp) MatrixExp {
+	return &Add{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Sub subtracts the right matrix from the left matrix.
+func (m1 *Vandermonde) Sub(m2 MatrixExp) MatrixExp {
+	return &Sub{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Scale performs scalar multiplication.
+func (m1 *Vandermonde) Scale(c float64) MatrixExp {
+	return &Scale{
+		C: c,
+		M: m1,
+	}
+}
+
+// Mul performs matrix multiplication.
+func (m1 *Vandermonde) Mul(m2 MatrixExp) MatrixExp {
+	return &Mul{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// MulElem performs element-wise multiplication.
+func (m1 *Vandermonde) MulElem(m2 MatrixExp) MatrixExp {
+	return &MulElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// DivElem performs element-wise division.
+func (m1 *Vandermonde) DivElem(m2 MatrixExp) MatrixExp {
+	return &DivElem{
+		Left:  m1,
+		Right: m2,
+	}
+}
+
+// Inv inverts a matrix.
+func (m1 *Vandermonde) Inv() MatrixExp {
+	return &Inv{m1}
+}